@@ -0,0 +1,74 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/types"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+)
+
+// ErrInsufficientLimits is returned if the funding accounts of a funder
+// cannot cover the share of a participant.
+var ErrInsufficientLimits = errors.New("funding account limits insufficient")
+
+// FundingAccount is an account from which a funder deposits a part of the
+// participant's share.
+type FundingAccount struct {
+	Account types.AccAddress
+	// Client sends the deposits of the account and must sign for it. It may
+	// only be nil for the funder's account, whose deposits are then sent
+	// with the funder's client.
+	Client client.Client
+	// Limit is the maximum amount deposited from the account per funding,
+	// by denomination. Denominations without a limit are not deposited from
+	// the account. If nil, the account is not limited.
+	Limit types.Coins
+}
+
+// splitShare splits the target amount of a deposit across the accounts. The
+// accounts are used in order, each up to its limit. It returns the part of
+// the target assigned to each account.
+func splitShare(accounts []FundingAccount, target types.Coins) ([]types.Coins, error) {
+	parts := make([]types.Coins, len(accounts))
+	rest := target
+	for i, acc := range accounts {
+		parts[i] = rest
+		if acc.Limit != nil {
+			parts[i] = capCoins(rest, acc.Limit)
+		}
+		rest = rest.Sub(parts[i])
+	}
+	if !rest.IsZero() {
+		return nil, fmt.Errorf("%w: %v not covered", ErrInsufficientLimits, rest)
+	}
+	return parts, nil
+}
+
+// missingParts returns the parts of the target that are still missing from
+// each account, given the existing deposit. As the accounts deposit in
+// order, the existing deposit is attributed to the accounts in order.
+func missingParts(parts []types.Coins, deposited types.Coins) []types.Coins {
+	missing := make([]types.Coins, len(parts))
+	rest := deposited
+	for i, p := range parts {
+		covered := capCoins(rest, p)
+		rest = rest.Sub(covered)
+		missing[i] = p.Sub(covered)
+	}
+	return missing
+}
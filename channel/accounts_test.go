@@ -0,0 +1,138 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/types"
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	pchannel "github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"perun.network/go-perun/channel"
+	ctest "perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+// signingClient is a client that, like a node client, can only send
+// transactions from a single account.
+type signingClient struct {
+	client.Client
+	acc types.AccAddress
+}
+
+func (c signingClient) ExecuteContract(ctx context.Context, in *wtypes.MsgExecuteContract, opts ...grpc.CallOption) (*wtypes.MsgExecuteContractResponse, error) {
+	if in.Sender != c.acc.String() {
+		return nil, fmt.Errorf("cannot sign for %s", in.Sender)
+	}
+	return c.Client.ExecuteContract(ctx, in, opts...)
+}
+
+// TestFunderAccounts tests that a funder splits the share of a participant
+// across its funding accounts.
+func TestFunderAccounts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	rec := &depositRecorder{Client: c}
+	r := test.NewRandomGenerator(maxNumParts, maxNumAssets, big.NewInt(maxFundingAmount), int64(maxChallengeDuration.Seconds()))
+
+	// newAccount returns a new funding account with the given limit that
+	// holds enough coins to fund any share.
+	newAccount := func(rng *rand.Rand, limit types.Coins) bchannel.FundingAccount {
+		acc := make(types.AccAddress, 20)
+		rng.Read(acc)
+		coins := types.NewCoins(types.NewInt64Coin("atom", maxFundingAmount), types.NewInt64Coin("osmo", maxFundingAmount))
+		require.NoError(t, c.AddCoins(ctx, acc, coins))
+		return bchannel.FundingAccount{Account: acc, Client: signingClient{Client: rec, acc: acc}, Limit: limit}
+	}
+	// newRequest returns a funding request for a new channel with a single
+	// participant, its funding ID and its share.
+	newRequest := func() (channel.FundingReq, binding.FundingID, types.Coins) {
+		params, state := r.NewParamsAndState(rng,
+			ctest.WithNumParts(1),
+			ctest.WithAssets(binding.Asset("atom"), binding.Asset("osmo")),
+			ctest.WithBalancesInRange(big.NewInt(100), big.NewInt(maxFundingAmount)))
+		req := newFundingRequest(ctx, params, state, 0, c)
+		fID, err := binding.CalcFundingID(params.ID(), params.Parts[0])
+		require.NoError(t, err)
+		share, err := binding.MakeCoins(state.Assets, pchannel.Balances(state.Balances).ForPart(0))
+		require.NoError(t, err)
+		return *req, fID, share
+	}
+	limit := types.NewCoins(types.NewInt64Coin("atom", 50), types.NewInt64Coin("osmo", 50))
+
+	t.Run("split", func(t *testing.T) {
+		hot, warm := newAccount(rng, limit), newAccount(rng, nil)
+		f, err := bchannel.NewFunder(ctx, signingClient{Client: rec, acc: c.Account()}, contract, c.Account(),
+			bchannel.FunderPollingIntervalOpt(polling),
+			bchannel.FunderAccountsOpt(hot, warm))
+		require.NoError(t, err)
+
+		req, fID, share := newRequest()
+		require.NoError(t, f.Fund(ctx, req))
+		deposits := rec.recorded()
+		require.Len(t, deposits, 2)
+		assert.Equal(t, hot.Account.String(), deposits[0].sender)
+		assert.True(t, limit.IsEqual(deposits[0].funds), "hot account deposits up to its limit")
+		assert.Equal(t, warm.Account.String(), deposits[1].sender)
+		assert.True(t, share.Sub(limit).IsEqual(deposits[1].funds), "warm account deposits the rest")
+		assert.True(t, share.IsEqual(queryDeposit(ctx, t, c, contract, fID)))
+
+		require.NoError(t, f.Fund(ctx, req))
+		assert.Empty(t, rec.recorded(), "repeated funding does not deposit")
+	})
+
+	t.Run("insufficient limits", func(t *testing.T) {
+		f, err := bchannel.NewFunder(ctx, rec, contract, c.Account(),
+			bchannel.FunderPollingIntervalOpt(polling),
+			bchannel.FunderAccountsOpt(newAccount(rng, limit), newAccount(rng, limit)))
+		require.NoError(t, err)
+
+		req, _, _ := newRequest()
+		// The share may be covered by the limits, so it is raised above.
+		req.Agreement = req.Agreement.Clone()
+		for _, bals := range req.Agreement {
+			bals[0].Add(bals[0], big.NewInt(100))
+		}
+		err = f.Fund(ctx, req)
+		assert.ErrorIs(t, err, bchannel.ErrInsufficientLimits)
+		assert.Empty(t, rec.recorded(), "no account deposits")
+	})
+	t.Run("missing client", func(t *testing.T) {
+		own := bchannel.FundingAccount{Account: c.Account(), Limit: limit}
+		accounts := []bchannel.FundingAccount{own, newAccount(rng, nil)}
+		_, err := bchannel.NewFunder(ctx, c, contract, c.Account(), bchannel.FunderAccountsOpt(accounts...))
+		assert.NoError(t, err, "funder's account")
+		assert.Nil(t, accounts[0].Client, "accounts are not modified")
+
+		other := newAccount(rng, nil)
+		other.Client = nil
+		_, err = bchannel.NewFunder(ctx, c, contract, c.Account(), bchannel.FunderAccountsOpt(own, other))
+		assert.Error(t, err, "other account")
+	})
+}
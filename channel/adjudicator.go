@@ -16,12 +16,19 @@ package channel
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/types"
 	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
 	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/proof"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/log"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/wallet"
 )
@@ -33,6 +40,7 @@ type Adjudicator struct {
 	*contractClient
 	contract client.ContractInstance
 	polling  time.Duration
+	blocks   *BlockWatcher
 }
 
 type AdjudicatorOpt func(*Adjudicator)
@@ -43,41 +51,185 @@ func AdjudicatorPollingIntervalOpt(d time.Duration) AdjudicatorOpt {
 	}
 }
 
-func NewAdjudicator(c client.Client, contract client.ContractInstance, acc types.AccAddress, opts ...AdjudicatorOpt) *Adjudicator {
+// AdjudicatorRetryPolicyOpt sets the policy for retrying failed client calls.
+func AdjudicatorRetryPolicyOpt(p RetryPolicy) AdjudicatorOpt {
+	return func(a *Adjudicator) {
+		a.retry = p
+	}
+}
+
+// AdjudicatorVerifiedQueriesOpt makes the adjudicator read disputes and
+// deposits with the given querier, which verifies the contract state against
+// trusted headers instead of trusting the node.
+func AdjudicatorVerifiedQueriesOpt(q *proof.Querier) AdjudicatorOpt {
+	return func(a *Adjudicator) {
+		a.verified = q
+	}
+}
+
+// AdjudicatorValidationOpt sets whether messages to and from the contract are
+// validated against its JSON schemas. Validation is enabled by default. It
+// catches encoding bugs early but costs time on every query, so it can be
+// disabled in production once the binding has been tested against the
+// deployed contract.
+func AdjudicatorValidationOpt(enabled bool) AdjudicatorOpt {
+	return func(a *Adjudicator) {
+		a.validate = enabled
+	}
+}
+
+// AdjudicatorBlockWatcherOpt sets the block watcher used for timeouts, e.g.,
+// the watcher of another adjudicator using the same client. By default, the
+// adjudicator creates its own block watcher.
+func AdjudicatorBlockWatcherOpt(w *BlockWatcher) AdjudicatorOpt {
+	return func(a *Adjudicator) {
+		a.blocks = w
+	}
+}
+
+// AdjudicatorLoggerOpt sets the logger. By default, go-perun's framework
+// logger is used.
+func AdjudicatorLoggerOpt(l log.Logger) AdjudicatorOpt {
+	return func(a *Adjudicator) {
+		a.log = l
+	}
+}
+
+// AdjudicatorMetricsOpt makes the adjudicator, its subscriptions and its
+// block watcher record metrics. By default, no metrics are recorded.
+func AdjudicatorMetricsOpt(m *metrics.Metrics) AdjudicatorOpt {
+	return func(a *Adjudicator) {
+		a.metrics = m
+	}
+}
+
+// AdjudicatorTracerProviderOpt sets the provider of the tracer used by the
+// adjudicator and its timeouts. By default, OpenTelemetry's global tracer
+// provider is used.
+func AdjudicatorTracerProviderOpt(tp trace.TracerProvider) AdjudicatorOpt {
+	return func(a *Adjudicator) {
+		a.tracer = tp.Tracer(TracerName)
+	}
+}
+
+// NewAdjudicator creates an adjudicator for the given contract. It returns an
+// error wrapping ErrIncompatibleContract if the contract is not supported.
+func NewAdjudicator(ctx context.Context, c client.Client, contract client.ContractInstance, acc types.AccAddress, opts ...AdjudicatorOpt) (*Adjudicator, error) {
+	cc, err := newContractClient(ctx, c, contract, acc)
+	if err != nil {
+		return nil, fmt.Errorf("creating contract client: %w", err)
+	}
 	a := &Adjudicator{
 		contract:       contract,
 		polling:        defaultPollingInterval,
-		contractClient: newContractClient(c, contract, acc),
+		contractClient: cc,
 	}
 	for _, opt := range opts {
 		opt(a)
 	}
-	return a
+	if a.blocks == nil {
+		a.blocks = newBlockWatcher(c, a.polling, a.retry, a.log)
+		a.blocks.metrics = a.metrics
+		a.blocks.tracer = a.tracer
+	}
+	return a, nil
+}
+
+// BlockWatcher returns the block watcher used for timeouts. It can be shared
+// with other adjudicators using the same client via
+// AdjudicatorBlockWatcherOpt.
+func (a *Adjudicator) BlockWatcher() *BlockWatcher {
+	return a.blocks
 }
 
 // Register registers the given ledger channel state on-chain.
 // If the channel has locked funds into sub-channels, the corresponding
 // signed sub-channel states must be provided.
-func (a *Adjudicator) Register(ctx context.Context, req channel.AdjudicatorReq, subChannels []channel.SignedState) error {
+//
+// If a state with a higher version is already registered, the state is not
+// submitted and a StateTransitionError reporting the on-chain version is
+// returned.
+func (a *Adjudicator) Register(ctx context.Context, req channel.AdjudicatorReq, subChannels []channel.SignedState) (err error) {
+	ctx, span := a.tracer.Start(ctx, SpanRegister, trace.WithAttributes(
+		channelIDAttribute(req.Params.ID()),
+		attribute.Int64(versionKey, int64(req.Tx.Version)),
+	))
+	defer func() { endSpan(span, err) }()
+
 	if len(subChannels) > 0 {
 		return fmt.Errorf("subchannels not supported")
 	}
-	return a.dispute(ctx, req)
+
+	l := a.channelLogger(req.Params.ID())
+	registered, err := a.checkRegister(ctx, req)
+	if err != nil || registered {
+		return err
+	}
+	l.Debugf("Registering version %d", req.Tx.Version)
+	err = a.dispute(ctx, l, req)
+	a.metrics.AdjudicatorCall(metrics.OpDispute, err)
+	if err != nil {
+		l.WithError(err).Errorf("Registering failed")
+	}
+	return makePerunError(err, req.Params.ID(), txTypeDispute)
 }
 
-func (a *Adjudicator) dispute(ctx context.Context, req channel.AdjudicatorReq) error {
-	return a.callAdjudicator(ctx, binding.NewDisputeExecuteMsg, req)
+// checkRegister checks the given request against the dispute that is
+// currently registered on-chain. It returns true if the state of the request
+// is already registered, also if the channel has been concluded with it.
+func (a *Adjudicator) checkRegister(ctx context.Context, req channel.AdjudicatorReq) (bool, error) {
+	id := req.Params.ID()
+	d, err := a.queryDispute(ctx, id)
+	if errors.Is(err, binding.ErrUnknownDispute) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("querying dispute: %w", err)
+	}
+
+	v := d.State.Version.Val()
+	switch {
+	case v == req.Tx.Version && d.State.PerunState().Equal(req.Tx.State) == nil:
+		return true, nil
+	case d.Concluded:
+		msg := fmt.Sprintf("channel concluded at version %d", v)
+		return false, channel.NewStateTransitionError(id, msg)
+	case v > req.Tx.Version:
+		msg := fmt.Sprintf("on-chain version %d is higher than %d", v, req.Tx.Version)
+		return false, channel.NewStateTransitionError(id, msg)
+	}
+	return false, nil
+}
+
+// channelLogger returns the logger with the channel ID field set.
+func (a *Adjudicator) channelLogger(id channel.ID) log.Logger {
+	return a.log.WithField(log.ChannelIDKey, hex.EncodeToString(id[:]))
+}
+
+func (a *Adjudicator) dispute(ctx context.Context, l log.Logger, req channel.AdjudicatorReq) error {
+	// The dispute has been applied if the state or a newer one is registered.
+	landed := func(ctx context.Context) (bool, error) {
+		d, err := a.queryDispute(ctx, req.Params.ID())
+		if errors.Is(err, binding.ErrUnknownDispute) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return d.Concluded || d.State.Version.Val() >= req.Tx.Version, nil
+	}
+	return a.callAdjudicator(ctx, l, a.enc.NewDisputeExecuteMsg, req, landed)
 }
 
 type AdjudicatorMsgFunc func(p channel.Params, s channel.State, sigs []wallet.Sig) ([]byte, error)
 
-func (a *Adjudicator) callAdjudicator(ctx context.Context, fn AdjudicatorMsgFunc, req channel.AdjudicatorReq) error {
+func (a *Adjudicator) callAdjudicator(ctx context.Context, l log.Logger, fn AdjudicatorMsgFunc, req channel.AdjudicatorReq, landed landedFunc) error {
+	_, span := a.tracer.Start(ctx, SpanMessage)
 	msg, err := fn(*req.Params, *req.Tx.State, req.Tx.Sigs)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
 
-	_, err = a.Execute(ctx, msg, nil)
+	_, err = a.execute(ctx, l, msg, nil, landed)
 	return err
 }
 
@@ -85,38 +237,112 @@ func (a *Adjudicator) callAdjudicator(ctx context.Context, fn AdjudicatorMsgFunc
 // final outcome is set on the asset holders and funds are withdrawn.
 // If the channel has locked funds in sub-channels, the states of the
 // corresponding sub-channels need to be supplied additionally.
-func (a *Adjudicator) Withdraw(ctx context.Context, req channel.AdjudicatorReq, subStates channel.StateMap) error {
+func (a *Adjudicator) Withdraw(ctx context.Context, req channel.AdjudicatorReq, subStates channel.StateMap) (err error) {
+	ctx, span := a.tracer.Start(ctx, SpanWithdraw, trace.WithAttributes(
+		channelIDAttribute(req.Params.ID()),
+		attribute.Int(log.PartIdxKey, int(req.Idx)),
+	))
+	defer func() { endSpan(span, err) }()
+
 	if len(subStates) > 0 {
 		return fmt.Errorf("subchannels not supported")
 	}
-	err := a.conclude(ctx, req)
+	l := a.channelLogger(req.Params.ID())
+	l.Debugf("Concluding")
+	err = a.conclude(ctx, l, req)
+	a.metrics.AdjudicatorCall(metrics.OpConclude, err)
+	if err != nil {
+		err = fmt.Errorf("concluding: %w", err)
+		l.WithError(err).Errorf("Withdrawing failed")
+		return makePerunError(err, req.Params.ID(), txTypeConclude)
+	}
+	l.Debugf("Withdrawing")
+	err = a.withdraw(ctx, l, req)
+	a.metrics.AdjudicatorCall(metrics.OpWithdraw, err)
 	if err != nil {
-		return fmt.Errorf("concluding: %w", err)
+		l.WithError(err).Errorf("Withdrawing failed")
 	}
-	return a.withdraw(ctx, req)
+	return makePerunError(err, req.Params.ID(), txTypeWithdraw)
 }
 
-func (a *Adjudicator) conclude(ctx context.Context, req channel.AdjudicatorReq) error {
-	return a.callAdjudicator(ctx, binding.NewConcludeExecuteMsg, req)
+func (a *Adjudicator) conclude(ctx context.Context, l log.Logger, req channel.AdjudicatorReq) (err error) {
+	ctx, span := a.tracer.Start(ctx, SpanConclude)
+	defer func() { endSpan(span, err) }()
+
+	// The conclusion has been applied if the channel is concluded.
+	landed := func(ctx context.Context) (bool, error) {
+		d, err := a.queryDispute(ctx, req.Params.ID())
+		if errors.Is(err, binding.ErrUnknownDispute) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return d.Concluded, nil
+	}
+	return a.callAdjudicator(ctx, l, a.enc.NewConcludeExecuteMsg, req, landed)
 }
 
-func (a *Adjudicator) withdraw(ctx context.Context, req channel.AdjudicatorReq) error {
-	w := binding.NewWithdrawal(req.Params.ID(), req.Acc.Address(), a.Account())
-	b := w.Bytes()
-	sig, err := req.Acc.SignData(b)
+func (a *Adjudicator) withdraw(ctx context.Context, l log.Logger, req channel.AdjudicatorReq) (err error) {
+	ctx, span := a.tracer.Start(ctx, SpanWithdrawFunds)
+	defer func() { endSpan(span, err) }()
+
+	_, signSpan := a.tracer.Start(ctx, SpanSign)
+	b, err := a.enc.WithdrawalBytes(req.Params.ID(), req.Acc.Address(), a.Account())
+	var sig wallet.Sig
+	if err == nil {
+		sig, err = req.Acc.SignData(b)
+	}
+	endSpan(signSpan, err)
 	if err != nil {
 		return fmt.Errorf("signing: %w", err)
 	}
 
-	msg, err := binding.NewWithdrawMsgExecute(req.Params.ID(), req.Acc.Address(), a.Account(), sig)
+	_, msgSpan := a.tracer.Start(ctx, SpanMessage)
+	msg, err := a.enc.NewWithdrawMsgExecute(req.Params.ID(), req.Acc.Address(), a.Account(), sig)
+	endSpan(msgSpan, err)
 	if err != nil {
 		return fmt.Errorf("creating message: %w", err)
 	}
 
-	_, err = a.Execute(ctx, msg, nil)
+	// The withdrawal has been applied if the deposit has been removed.
+	landed := func(ctx context.Context) (bool, error) {
+		fID, err := binding.CalcFundingID(req.Params.ID(), req.Acc.Address())
+		if err != nil {
+			return false, err
+		}
+		deposit, err := a.queryDepositByID(ctx, fID)
+		return deposit.Empty(), err
+	}
+
+	_, err = a.execute(ctx, l, msg, nil, landed)
 	return err
 }
 
+// queryDispute queries the dispute that is registered for the given channel.
+func (a *Adjudicator) queryDispute(ctx context.Context, ch channel.ID) (binding.DisputeQueryResponse, error) {
+	if a.verified != nil {
+		b, err := a.queryVerified(ctx, a.enc.DisputeKey(ch))
+		if err != nil {
+			return binding.DisputeQueryResponse{}, err
+		} else if b == nil {
+			return binding.DisputeQueryResponse{}, binding.ErrUnknownDispute
+		}
+		return a.enc.DecodeDisputeQueryResponse(b, a.decodeOpt())
+	}
+
+	q, err := a.enc.NewDisputeQueryMsg(ch)
+	if err != nil {
+		return binding.DisputeQueryResponse{}, err
+	}
+
+	resp, err := a.Query(ctx, q)
+	if err != nil {
+		return binding.DisputeQueryResponse{}, err
+	}
+
+	return a.enc.DecodeDisputeQueryResponse(resp.Data, a.decodeOpt())
+}
+
 // Progress progresses the state of a previously registered channel on-chain.
 // The signatures for the old state can be nil as the state is already
 // registered on the adjudicator.
@@ -25,6 +25,8 @@ import (
 	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
 	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/simulation"
 	ptest "github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"perun.network/go-perun/channel"
 	ctest "perun.network/go-perun/channel/test"
 	pkgtest "perun.network/go-perun/pkg/test"
@@ -41,23 +43,44 @@ func TestAdjudicator(t *testing.T) {
 	c, contract := test.NewTestClientWithContract(ctx, t)
 	c.StartTicking(blockTick, simChainTick)
 	defer c.StopTicking()
-	a := newAdjudicatorSetup(c, contract)
+	a := newAdjudicatorSetup(ctx, c, c, contract)
+	ptest.TestAdjudicatorWithSubscription(ctx, t, rng, a)
+}
+
+// TestAdjudicatorWithoutValidation tests the adjudicator with schema
+// validation disabled.
+func TestAdjudicatorWithoutValidation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	c.StartTicking(blockTick, simChainTick)
+	defer c.StopTicking()
+	a := newAdjudicatorSetup(ctx, c, c, contract, bchannel.AdjudicatorValidationOpt(false))
 	ptest.TestAdjudicatorWithSubscription(ctx, t, rng, a)
 }
 
 type adjudicatorSetup struct {
 	c        *simulation.Client
+	cc       client.Client // The client used by the adjudicator and funder.
 	contract client.ContractInstance
 	adj      channel.Adjudicator
 	r        *test.RandomGenerator
 	w        wtest.Wallet
 }
 
-func newAdjudicatorSetup(c *simulation.Client, contract client.ContractInstance) *adjudicatorSetup {
-	opt := bchannel.AdjudicatorPollingIntervalOpt(polling)
-	adj := bchannel.NewAdjudicator(c, contract, c.Account(), opt)
+// newAdjudicatorSetup creates an adjudicator setup whose adjudicator and
+// funder use client cc. The options are applied to the adjudicator.
+func newAdjudicatorSetup(ctx context.Context, c *simulation.Client, cc client.Client, contract client.ContractInstance, opts ...bchannel.AdjudicatorOpt) *adjudicatorSetup {
+	opts = append([]bchannel.AdjudicatorOpt{bchannel.AdjudicatorPollingIntervalOpt(polling)}, opts...)
+	adj, err := bchannel.NewAdjudicator(ctx, cc, contract, c.Account(), opts...)
+	if err != nil {
+		panic(err)
+	}
 	return &adjudicatorSetup{
 		c:        c,
+		cc:       cc,
 		contract: contract,
 		adj:      &testAdjudicator{adj},
 		r:        test.NewRandomGenerator(maxNumParts, maxNumAssets, big.NewInt(maxFundingAmount), int64(maxChallengeDuration.Seconds())),
@@ -118,8 +141,11 @@ func (a *adjudicatorSetup) fund(ctx context.Context, params *channel.Params, sta
 	}
 
 	opt := bchannel.FunderPollingIntervalOpt(polling)
-	f := bchannel.NewFunder(a.c, a.contract, a.c.Account(), opt)
-	err := fundAll(ctx, f, requests)
+	f, err := bchannel.NewFunder(ctx, a.cc, a.contract, a.c.Account(), opt)
+	if err != nil {
+		panic(err)
+	}
+	err = fundAll(ctx, f, requests)
 	if err != nil {
 		panic(err)
 	}
@@ -143,3 +169,130 @@ func fundAll(ctx context.Context, f channel.Funder, requests []*channel.FundingR
 	}
 	return nil
 }
+
+// TestAdjudicatorRegisterConflict tests that registering an outdated state
+// fails with a state transition error.
+func TestAdjudicatorRegisterConflict(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	a := newAdjudicatorSetup(ctx, c, c, contract)
+	params, state := a.NewFundedChannel(ctx, rng)
+
+	register := func(version uint64) error {
+		s := state.Clone()
+		s.Version = version
+		req := channel.AdjudicatorReq{
+			Params: &params,
+			Tx: channel.Transaction{
+				State: s,
+				Sigs:  a.SignState(s, params.Parts),
+			},
+		}
+		return a.adj.Register(ctx, req, nil)
+	}
+
+	require.NoError(t, register(2), "register version 2")
+	require.NoError(t, register(2), "register version 2 again")
+	err := register(1)
+	require.Error(t, err, "register version 1")
+	assert.True(t, channel.IsStateTransitionError(err), "state transition error")
+}
+
+// TestAdjudicatorRegisterConcluded tests that registering the state with
+// which a channel has been concluded succeeds, while registering any other
+// state fails with a state transition error.
+func TestAdjudicatorRegisterConcluded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	a := newAdjudicatorSetup(ctx, c, c, contract)
+	params, state := a.NewFundedChannel(ctx, rng)
+
+	request := func(version uint64, final bool) channel.AdjudicatorReq {
+		s := state.Clone()
+		s.Version = version
+		s.IsFinal = final
+		return channel.AdjudicatorReq{
+			Params: &params,
+			Acc:    a.Account(params.Parts[0]),
+			Idx:    0,
+			Tx: channel.Transaction{
+				State: s,
+				Sigs:  a.SignState(s, params.Parts),
+			},
+		}
+	}
+
+	final := request(2, true)
+	require.NoError(t, a.adj.Withdraw(ctx, final, nil), "withdraw final state")
+	assert.NoError(t, a.adj.Register(ctx, final, nil), "register concluded state")
+
+	err := a.adj.Register(ctx, request(2, false), nil)
+	require.Error(t, err, "register other state at concluded version")
+	assert.True(t, channel.IsStateTransitionError(err), "state transition error")
+	err = a.adj.Register(ctx, request(3, false), nil)
+	require.Error(t, err, "register newer version")
+	assert.True(t, channel.IsStateTransitionError(err), "state transition error")
+}
+
+// TestAdjudicatorSharedBlockWatcher tests that adjudicators only share a
+// block watcher if it is passed explicitly.
+func TestAdjudicatorSharedBlockWatcher(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	newAdjudicator := func(opts ...bchannel.AdjudicatorOpt) *bchannel.Adjudicator {
+		adj, err := bchannel.NewAdjudicator(ctx, c, contract, c.Account(), opts...)
+		require.NoError(t, err)
+		return adj
+	}
+
+	a := newAdjudicator(bchannel.AdjudicatorPollingIntervalOpt(polling))
+	assert.NotSame(t, a.BlockWatcher(), newAdjudicator(bchannel.AdjudicatorPollingIntervalOpt(polling)).BlockWatcher(), "own watcher")
+	assert.Same(t, a.BlockWatcher(), newAdjudicator(bchannel.AdjudicatorBlockWatcherOpt(a.BlockWatcher())).BlockWatcher(), "shared watcher")
+}
+
+// TestAdjudicatorSnapshot tests disputing different versions from the same
+// funded channel by reverting the simulated chain.
+func TestAdjudicatorSnapshot(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	a := newAdjudicatorSetup(ctx, c, c, contract)
+	params, state := a.NewFundedChannel(ctx, rng)
+
+	register := func(version uint64) error {
+		s := state.Clone()
+		s.Version = version
+		req := channel.AdjudicatorReq{
+			Params: &params,
+			Tx: channel.Transaction{
+				State: s,
+				Sigs:  a.SignState(s, params.Parts),
+			},
+		}
+		return a.adj.Register(ctx, req, nil)
+	}
+
+	blockTime := c.BlockTime()
+	snapshot := c.Snapshot()
+	require.NoError(t, register(7), "register version 7")
+	c.SetBlockTime(blockTime.Add(simChainTick))
+	require.Error(t, register(5), "register version 5 after version 7")
+
+	require.NoError(t, c.Revert(snapshot), "revert")
+	assert.Equal(t, blockTime, c.BlockTime(), "block time")
+	require.NoError(t, register(5), "register version 5")
+
+	require.NoError(t, c.Revert(snapshot), "revert again")
+	require.NoError(t, register(7), "register version 7 again")
+	require.Error(t, c.Revert(snapshot+1), "revert unknown snapshot")
+}
@@ -16,6 +16,7 @@ package channel
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"io"
 
 	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
@@ -39,15 +40,21 @@ func (*Backend) CalcID(p *channel.Params) channel.ID {
 
 // Sign signs a channel's State with the given Account.
 func (*Backend) Sign(a wallet.Account, s *channel.State) (wallet.Sig, error) {
-	b := binding.NewState(s).Bytes()
-	return a.SignData(b)
+	_s, err := binding.NewState(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state: %w", err)
+	}
+	return a.SignData(_s.Bytes())
 }
 
 // Verify verifies that the provided signature on the state belongs to the
 // provided address.
 func (b *Backend) Verify(addr wallet.Address, s *channel.State, sig wallet.Sig) (bool, error) {
-	msg := binding.NewState(s).Bytes()
-	return wallet.VerifySignature(msg, sig, addr)
+	_s, err := binding.NewState(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid state: %w", err)
+	}
+	return wallet.VerifySignature(_s.Bytes(), sig, addr)
 }
 
 // DecodeAsset decodes an asset from a stream.
@@ -15,10 +15,19 @@
 package channel_test
 
 import (
+	"io"
+	"math/big"
 	"testing"
 
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
 	"github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
 	ctest "perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+	wtest "perun.network/go-perun/wallet/test"
 )
 
 // TestBackend tests the backend.
@@ -29,3 +38,48 @@ func TestBackend(t *testing.T) {
 	}
 	test.TestChannelBackend(t, opts...)
 }
+
+// foreignAsset is an asset of another backend.
+type foreignAsset struct{}
+
+func (foreignAsset) Encode(io.Writer) error { return nil }
+
+// TestBackendInvalidState tests that states that cannot be represented in the
+// contract, such as states proposed by a malicious peer, are rejected with an
+// error.
+func TestBackendInvalidState(t *testing.T) {
+	rng := pkgtest.Prng(t)
+	b := bchannel.NewBackend()
+	acc := wtest.NewRandomAccount(rng)
+
+	tests := []struct {
+		name   string
+		modify func(*channel.State)
+		err    error
+	}{
+		{"locked funds", func(s *channel.State) {
+			s.Locked = []channel.SubAlloc{*channel.NewSubAlloc(channel.ID{}, []channel.Bal{big.NewInt(1)}, nil)}
+		}, binding.ErrInvalidAllocation},
+		{"no balances", func(s *channel.State) { s.Balances = nil }, binding.ErrInvalidAllocation},
+		{"missing asset", func(s *channel.State) { s.Assets = s.Assets[1:] }, binding.ErrInvalidAllocation},
+		{"ragged balances", func(s *channel.State) { s.Balances[0] = s.Balances[0][1:] }, binding.ErrInvalidAllocation},
+		{"nil balance", func(s *channel.State) { s.Balances[1][0] = nil }, binding.ErrInvalidAllocation},
+		{"negative balance", func(s *channel.State) { s.Balances[0][0] = big.NewInt(-1) }, binding.ErrInvalidAllocation},
+		{"large balance", func(s *channel.State) { s.Balances[0][0] = new(big.Int).Lsh(big.NewInt(1), 128) }, binding.ErrInvalidAllocation},
+		{"foreign asset", func(s *channel.State) { s.Assets[0] = foreignAsset{} }, binding.ErrInvalidAsset},
+		{"invalid denom", func(s *channel.State) { s.Assets[0] = binding.Asset("1nvalid") }, binding.ErrInvalidAsset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, s := ctest.NewRandomParamsAndState(rng, ctest.WithNumParts(2), ctest.WithNumAssets(2), ctest.WithNumLocked(0), ctest.WithoutApp())
+			_, err := b.Sign(acc, s)
+			require.NoError(t, err, "valid state")
+
+			tt.modify(s)
+			_, err = b.Sign(acc, s)
+			assert.ErrorIs(t, err, tt.err, "sign")
+			_, err = b.Verify(acc.Address(), s, make([]byte, 64))
+			assert.ErrorIs(t, err, tt.err, "verify")
+		})
+	}
+}
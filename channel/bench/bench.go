@@ -0,0 +1,152 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package bench measures the gas consumed by the execute messages of the
+// Perun contract for channels of different sizes.
+//
+// The channel and wallet backends of go-perun must be set to the backends of
+// this module before running a benchmark.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"math/rand"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/types"
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/simulation"
+	bwallet "github.com/perun-network/perun-cosmwasm-backend/wallet"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+// Execute messages of the contract, named by their JSON field.
+const (
+	MsgDeposit  = "deposit"
+	MsgDispute  = "dispute"
+	MsgConclude = "conclude"
+	MsgWithdraw = "withdraw"
+)
+
+// Msgs are the measured execute messages.
+var Msgs = []string{MsgDeposit, MsgDispute, MsgConclude, MsgWithdraw}
+
+// Grid is the set of channel sizes to measure.
+type Grid struct {
+	Parts  []int // The numbers of participants.
+	Assets []int // The numbers of assets.
+}
+
+// Range returns the integers from min to max, both inclusive.
+func Range(min, max int) []int {
+	r := make([]int, 0, max-min+1)
+	for i := min; i <= max; i++ {
+		r = append(r, i)
+	}
+	return r
+}
+
+// Result is the gas consumed by a message for a channel size.
+type Result struct {
+	Parts  int    `json:"parts"`
+	Assets int    `json:"assets"`
+	Msg    string `json:"msg"`
+	Gas    uint64 `json:"gas"`
+}
+
+// Run measures the gas consumed by each message in Msgs for each channel size
+// in the grid by simulating the messages on the given client. The messages
+// are measured for the first participant. As all participants hold the same
+// funds, the deposits and withdrawals of the others cost the same.
+func Run(ctx context.Context, c *simulation.Client, contract client.ContractInstance, grid Grid) (Report, error) {
+	// The simulated gas is reported as is, so that the report reflects the
+	// contract and not the configuration of the estimator.
+	e := bchannel.NewEstimator(c, contract, c.Account(), types.NewInt64DecCoin("stake", 0),
+		bchannel.EstimatorGasAdjustmentOpt(1))
+	var report Report
+	for _, numParts := range grid.Parts {
+		for _, numAssets := range grid.Assets {
+			params, state, err := newChannel(numParts, numAssets)
+			if err != nil {
+				return nil, fmt.Errorf("creating channel with %d participants and %d assets: %w", numParts, numAssets, err)
+			}
+			coins, err := bchannel.EstimationFunds(state, 0)
+			if err != nil {
+				return nil, fmt.Errorf("creating coins: %w", err)
+			}
+			if err := c.AddCoins(ctx, c.Account(), coins); err != nil {
+				return nil, fmt.Errorf("adding coins: %w", err)
+			}
+
+			est, err := e.Estimate(ctx, params, state, 0)
+			if err != nil {
+				return nil, fmt.Errorf("estimating channel with %d participants and %d assets: %w", numParts, numAssets, err)
+			}
+			for _, r := range []struct {
+				msg  string
+				cost bchannel.Cost
+			}{
+				{MsgDeposit, est.Deposit},
+				{MsgDispute, est.Dispute},
+				{MsgConclude, est.Conclude},
+				{MsgWithdraw, est.Withdraw},
+			} {
+				report = append(report, Result{Parts: numParts, Assets: numAssets, Msg: r.msg, Gas: r.cost.Gas})
+			}
+		}
+	}
+	return report, nil
+}
+
+// newChannel creates a channel with the given numbers of participants and
+// assets in which every participant holds one unit of every asset.
+func newChannel(numParts, numAssets int) (*channel.Params, *channel.State, error) {
+	rng := rand.New(rand.NewSource(0))
+	w := bwallet.NewWallet(keyring.NewInMemory())
+	parts := make([]wallet.Address, numParts)
+	for i := range parts {
+		acc, err := w.NewAccount(rng, fmt.Sprintf("part%d", i), "")
+		if err != nil {
+			return nil, nil, err
+		}
+		parts[i] = acc.Address()
+	}
+	params, err := channel.NewParams(60, parts, channel.NoApp(), big.NewInt(0), true, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	assets := make([]channel.Asset, numAssets)
+	bals := make([][]channel.Bal, numAssets)
+	for i := range assets {
+		assets[i] = binding.Asset(fmt.Sprintf("asset%d", i))
+		bals[i] = make([]channel.Bal, numParts)
+		for j := range bals[i] {
+			bals[i][j] = big.NewInt(1)
+		}
+	}
+	state := &channel.State{
+		ID:         params.ID(),
+		Version:    0,
+		App:        channel.NoApp(),
+		Allocation: channel.Allocation{Assets: assets, Balances: bals},
+		Data:       channel.NoData(),
+	}
+	return params, state, nil
+}
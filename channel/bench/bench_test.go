@@ -0,0 +1,134 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package bench_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/bench"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	bwallet "github.com/perun-network/perun-cosmwasm-backend/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+const maxNumParts = 16 // The maximum number of participants in a channel.
+const maxNumAssets = 8 // The maximum number of assets used in a channel.
+const testTimeout = 5 * time.Minute
+
+var (
+	csvReport  = flag.String("bench.csv", "", "write the gas report as CSV to the given file")
+	jsonReport = flag.String("bench.json", "", "write the gas report as JSON to the given file")
+)
+
+func init() {
+	channel.SetBackend(bchannel.NewBackend())
+	wallet.SetBackend(bwallet.NewBackend())
+}
+
+// TestBudgets checks the gas consumption of the contract against the budgets
+// in testdata/budgets.json. In short mode, only the corners of the grid are
+// measured.
+func TestBudgets(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	f, err := os.Open("testdata/budgets.json")
+	require.NoError(t, err)
+	defer f.Close()
+	budgets, err := bench.ReadBudgets(f)
+	require.NoError(t, err)
+
+	grid := bench.Grid{Parts: bench.Range(2, maxNumParts), Assets: bench.Range(1, maxNumAssets)}
+	if testing.Short() {
+		grid = bench.Grid{Parts: []int{2, maxNumParts}, Assets: []int{1, maxNumAssets}}
+	}
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	report, err := bench.Run(ctx, c, contract, grid)
+	require.NoError(t, err)
+	require.Len(t, report, len(grid.Parts)*len(grid.Assets)*len(bench.Msgs))
+
+	writeReport(t, *csvReport, report.WriteCSV)
+	writeReport(t, *jsonReport, report.WriteJSON)
+	assert.NoError(t, report.Check(budgets))
+}
+
+func writeReport(t *testing.T, path string, write func(w io.Writer) error) {
+	if path == "" {
+		return
+	}
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, write(f))
+}
+
+func TestReport(t *testing.T) {
+	report := bench.Report{
+		{Parts: 2, Assets: 1, Msg: bench.MsgDispute, Gas: 100},
+		{Parts: 4, Assets: 2, Msg: bench.MsgDispute, Gas: 300},
+		{Parts: 4, Assets: 2, Msg: bench.MsgDeposit, Gas: 1000},
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteCSV(&buf))
+		records, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"parts", "assets", "msg", "gas"},
+			{"2", "1", "dispute", "100"},
+			{"4", "2", "dispute", "300"},
+			{"4", "2", "deposit", "1000"},
+		}, records)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteJSON(&buf))
+		var decoded bench.Report
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, report, decoded)
+	})
+
+	t.Run("Check", func(t *testing.T) {
+		// The deposit has no budget. The limit for 2 participants and 1 asset
+		// is 100 and the limit for 4 participants and 2 assets is 250.
+		budgets := bench.Budgets{bench.MsgDispute: {Base: 50, PerPartAsset: 25}}
+		err := report.Check(budgets)
+		var budgetErr *bench.BudgetError
+		require.ErrorAs(t, err, &budgetErr)
+		assert.Len(t, budgetErr.Exceeded, 1)
+
+		budgets[bench.MsgDispute] = bench.Budget{Base: 100, PerPartAsset: 25}
+		assert.NoError(t, report.Check(budgets))
+	})
+
+	t.Run("ReadBudgets", func(t *testing.T) {
+		_, err := bench.ReadBudgets(bytes.NewBufferString(`{"dispute": {"base": 1, "per_parts": 2}}`))
+		assert.Error(t, err, "unknown field")
+	})
+}
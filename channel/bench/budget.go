@@ -0,0 +1,50 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package bench
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Budget is the maximum gas that a message may consume, given as a linear
+// function of the number of participants and assets.
+type Budget struct {
+	Base         uint64 `json:"base"`
+	PerPart      uint64 `json:"per_part"`
+	PerAsset     uint64 `json:"per_asset"`
+	PerPartAsset uint64 `json:"per_part_asset"` // Per participant and asset.
+}
+
+// Limit returns the budget for a channel with the given numbers of
+// participants and assets.
+func (b Budget) Limit(parts, assets int) uint64 {
+	p, a := uint64(parts), uint64(assets)
+	return b.Base + b.PerPart*p + b.PerAsset*a + b.PerPartAsset*p*a
+}
+
+// Budgets are the budgets of the messages, by message name.
+type Budgets map[string]Budget
+
+// ReadBudgets reads budgets in JSON format.
+func ReadBudgets(r io.Reader) (Budgets, error) {
+	var b Budgets
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,86 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Report is the result of a benchmark run.
+type Report []Result
+
+// WriteCSV writes the report as CSV with a header line.
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"parts", "assets", "msg", "gas"}); err != nil {
+		return err
+	}
+	for _, res := range r {
+		if err := cw.Write([]string{
+			strconv.Itoa(res.Parts),
+			strconv.Itoa(res.Assets),
+			res.Msg,
+			strconv.FormatUint(res.Gas, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the report as a JSON array of results.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Check returns an error listing all results that exceed their budget.
+// Results of messages without budget are not checked.
+func (r Report) Check(budgets Budgets) error {
+	var exceeded []string
+	for _, res := range r {
+		b, ok := budgets[res.Msg]
+		if !ok {
+			continue
+		}
+		if limit := b.Limit(res.Parts, res.Assets); res.Gas > limit {
+			exceeded = append(exceeded, fmt.Sprintf("%s with %d participants and %d assets: %d > %d",
+				res.Msg, res.Parts, res.Assets, res.Gas, limit))
+		}
+	}
+	if len(exceeded) > 0 {
+		return &BudgetError{Exceeded: exceeded}
+	}
+	return nil
+}
+
+// BudgetError is returned by Report.Check if results exceed their budget.
+type BudgetError struct {
+	Exceeded []string // Descriptions of the results that exceed their budget.
+}
+
+func (e *BudgetError) Error() string {
+	msg := fmt.Sprintf("%d results exceed their gas budget", len(e.Exceeded))
+	for _, x := range e.Exceeded {
+		msg += "\n\t" + x
+	}
+	return msg
+}
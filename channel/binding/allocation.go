@@ -24,28 +24,60 @@ import (
 )
 
 // MakeCoins translates assets and balances into coins.
-func MakeCoins(assets []channel.Asset, bals []channel.Bal) types.Coins {
+func MakeCoins(assets []channel.Asset, bals []channel.Bal) (types.Coins, error) {
 	if len(assets) != len(bals) {
-		panic("mismatch")
+		return nil, fmt.Errorf("%w: %d assets but %d balances", ErrInvalidAllocation, len(assets), len(bals))
 	}
 	coinsList := make([]types.Coin, len(assets))
 	for i, bal := range bals {
-		denom := makeDenom(assets[i])
-		amount := types.NewIntFromBigInt(bal)
-		coinsList[i] = types.NewCoin(denom, amount)
+		denom, err := makeDenom(assets[i])
+		if err != nil {
+			return nil, err
+		}
+		amount, err := newUint128(bal)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAllocation, err)
+		}
+		coinsList[i] = types.Coin{Denom: denom, Amount: amount.Int()}
 	}
-	return types.NewCoins(coinsList...)
+	coins, err := newCoins(coinsList)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAsset, err)
+	}
+	return coins, nil
+}
+
+// newCoins is like types.NewCoins, but returns an error instead of panicking
+// on invalid coins.
+func newCoins(list []types.Coin) (types.Coins, error) {
+	coins := make(types.Coins, 0, len(list))
+	for _, c := range list {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		if !c.IsZero() {
+			coins = append(coins, c)
+		}
+	}
+	coins = coins.Sort()
+	if err := coins.Validate(); err != nil {
+		return nil, err
+	}
+	return coins, nil
 }
 
 // Denom represents an asset denomination.
 type Denom = string
 
-func makeDenom(a channel.Asset) Denom {
+func makeDenom(a channel.Asset) (Denom, error) {
 	denom, ok := a.(Asset)
 	if !ok {
-		panic(fmt.Sprintf("invalid type: %T", a))
+		return "", fmt.Errorf("%w: type %T", ErrInvalidAsset, a)
+	}
+	if err := types.ValidateDenom(string(denom)); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidAsset, err)
 	}
-	return Denom(denom)
+	return Denom(denom), nil
 }
 
 // Asset represents an asset.
@@ -0,0 +1,184 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package binding
+
+import (
+	"encoding/base64"
+	"strconv"
+	"unicode/utf8"
+)
+
+// The canonical encoding is the JSON Canonicalization Scheme (JCS, RFC 8785)
+// applied to the JSON encoding of an object. The encoders in this file write
+// it directly instead of marshalling the object and transforming the result.
+// Object keys must be written in the order of their UTF-16 code units, which
+// for the ASCII keys used here is their lexicographic order.
+
+// canonicalEncoder is implemented by objects that can append their canonical
+// encoding to a byte slice.
+type canonicalEncoder interface {
+	appendCanonical(b []byte) []byte
+}
+
+// encodeCanonical creates a canonical encoding of an object.
+func encodeCanonical(o canonicalEncoder) []byte {
+	return o.appendCanonical(nil)
+}
+
+func (s *State) appendCanonical(b []byte) []byte {
+	b = append(b, `{"balances":`...)
+	b = s.Balances.appendCanonical(b)
+	b = append(b, `,"channel_id":`...)
+	b = s.ChannelID.appendCanonical(b)
+	b = append(b, `,"finalized":`...)
+	b = strconv.AppendBool(b, s.Finalized)
+	b = append(b, `,"version":`...)
+	b = s.Version.appendCanonical(b)
+	return append(b, '}')
+}
+
+func (bals Balances) appendCanonical(b []byte) []byte {
+	if bals == nil {
+		return append(b, "null"...)
+	}
+	b = append(b, '[')
+	for i, bal := range bals {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = bal.appendCanonical(b)
+	}
+	return append(b, ']')
+}
+
+func (bal Balance) appendCanonical(b []byte) []byte {
+	if bal == nil {
+		return append(b, "null"...)
+	}
+	b = append(b, '[')
+	for i, c := range bal {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = c.appendCanonical(b)
+	}
+	return append(b, ']')
+}
+
+func (c *Coin) appendCanonical(b []byte) []byte {
+	b = append(b, `{"amount":`...)
+	b = c.Amount.appendCanonical(b)
+	b = append(b, `,"denom":`...)
+	b = appendCanonicalString(b, c.Denom)
+	return append(b, '}')
+}
+
+func (p *Params) appendCanonical(b []byte) []byte {
+	b = append(b, `{"dispute_duration":`...)
+	b = p.DisputeDuration.appendCanonical(b)
+	b = append(b, `,"nonce":`...)
+	b = p.Nonce.appendCanonical(b)
+	b = append(b, `,"participants":`...)
+	if p.Parts == nil {
+		b = append(b, "null"...)
+	} else {
+		b = append(b, '[')
+		for i, part := range p.Parts {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = part.appendCanonical(b)
+		}
+		b = append(b, ']')
+	}
+	return append(b, '}')
+}
+
+func (f *funding) appendCanonical(b []byte) []byte {
+	b = append(b, `{"channel":`...)
+	b = f.Channel.appendCanonical(b)
+	b = append(b, `,"part":`...)
+	b = f.Part.appendCanonical(b)
+	return append(b, '}')
+}
+
+func (r *DisputeQueryResponseMsg) appendCanonical(b []byte) []byte {
+	b = append(b, `{"concluded":`...)
+	b = strconv.AppendBool(b, r.Concluded)
+	b = append(b, `,"state":`...)
+	b = r.State.appendCanonical(b)
+	b = append(b, `,"timeout":`...)
+	b = r.Timeout.appendCanonical(b)
+	return append(b, '}')
+}
+
+func (i Uint128) appendCanonical(b []byte) []byte {
+	b = append(b, '"')
+	if i.val == nil {
+		b = append(b, "<nil>"...)
+	} else {
+		b = i.val.Append(b, 10)
+	}
+	return append(b, '"')
+}
+
+func (i Uint64) appendCanonical(b []byte) []byte {
+	b = append(b, '"')
+	b = strconv.AppendUint(b, uint64(i), 10)
+	return append(b, '"')
+}
+
+func (a ByteArray) appendCanonical(b []byte) []byte {
+	n := len(b) + 1
+	b = append(b, make([]byte, base64.StdEncoding.EncodedLen(len(a))+2)...)
+	b[n-1] = '"'
+	base64.StdEncoding.Encode(b[n:], a)
+	b[len(b)-1] = '"'
+	return b
+}
+
+// appendCanonicalString appends the canonical encoding of a string. Like
+// json.Marshal, it replaces invalid UTF-8 bytes by the replacement character.
+// Unlike json.Marshal, it escapes only quotes, backslashes and control
+// characters, as required by JCS.
+func appendCanonicalString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for _, r := range s { // Invalid bytes are decoded as utf8.RuneError.
+		switch r {
+		case '"', '\\':
+			b = append(b, '\\', byte(r))
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			if r < 0x20 {
+				b = append(b, '\\', 'u', '0', '0', hex[r>>4], hex[r&0xf])
+			} else {
+				var buf [utf8.UTFMax]byte
+				n := utf8.EncodeRune(buf[:], r)
+				b = append(b, buf[:n]...)
+			}
+		}
+	}
+	return append(b, '"')
+}
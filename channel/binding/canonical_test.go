@@ -0,0 +1,200 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package binding_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/gowebpki/jcs"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+)
+
+// encodeReference returns the canonical encoding of an object by marshalling
+// it and transforming the result, which is the reference for the encoders of
+// the binding.
+func encodeReference(t testing.TB, o interface{}) []byte {
+	b, err := json.Marshal(o)
+	require.NoError(t, err)
+	b, err = jcs.Transform(b)
+	require.NoError(t, err)
+	return b
+}
+
+// newState creates a state with the given numbers of participants and assets
+// in which every balance has the given amount. The state is created directly,
+// so that the denomination need not be valid.
+func newState(id []byte, version uint64, final bool, denom string, amount *big.Int, numParts, numAssets int) *binding.State {
+	var _amount binding.Uint128
+	if err := _amount.UnmarshalJSON([]byte(`"` + amount.String() + `"`)); err != nil {
+		panic(err)
+	}
+	bals := make(binding.Balances, numParts)
+	for i := range bals {
+		bals[i] = make(binding.Balance, numAssets)
+		for j := range bals[i] {
+			bals[i][j] = binding.Coin{Denom: denom, Amount: _amount}
+		}
+	}
+	var cID channel.ID
+	copy(cID[:], id)
+	return &binding.State{
+		Balances:  bals,
+		ChannelID: cID[:],
+		Finalized: final,
+		Version:   binding.Uint64(version),
+	}
+}
+
+// newParams creates parameters whose participants are the given number of
+// consecutive chunks of the given bytes.
+func newParams(duration uint64, nonce []byte, parts []byte, numParts int) *binding.Params {
+	p := &binding.Params{DisputeDuration: binding.Uint64(duration), Nonce: nonce}
+	if numParts > 0 {
+		p.Parts = make([]binding.OffIdentity, numParts)
+		size := len(parts) / numParts
+		for i := range p.Parts {
+			p.Parts[i] = parts[i*size : (i+1)*size]
+		}
+	}
+	return p
+}
+
+// uint128 returns the given bytes as an integer in the range of Uint128.
+func uint128(b []byte) *big.Int {
+	if len(b) > 16 {
+		b = b[:16]
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+func TestCanonicalEncoding(t *testing.T) {
+	t.Run("zero", func(t *testing.T) {
+		var s binding.State
+		assert.Equal(t, encodeReference(t, &s), s.Bytes())
+		var p binding.Params
+		assert.Equal(t, encodeReference(t, &p), p.Bytes())
+		assert.True(t, binding.DisputeQueryResponse{}.Equal(binding.DisputeQueryResponse{}))
+	})
+
+	t.Run("escaping", func(t *testing.T) {
+		for _, denom := range []string{"<>&", "\"\\/", "\b\f\n\r\t\x00\x1f\x7f", "  ", "ä€😀", "\xff\xc3"} {
+			s := newState([]byte{1}, 1, false, denom, big.NewInt(1), 2, 1)
+			assert.Equal(t, string(encodeReference(t, s)), string(s.Bytes()), "denom %q", denom)
+		}
+	})
+
+	t.Run("Equal", func(t *testing.T) {
+		a := binding.DisputeQueryResponse{binding.DisputeQueryResponseMsg{
+			State:   *newState([]byte{1}, 2, false, "stake", big.NewInt(3), 2, 2),
+			Timeout: 4,
+		}}
+		b := a
+		b.State = *newState([]byte{1}, 2, false, "stake", big.NewInt(3), 2, 2)
+		assert.True(t, a.Equal(b))
+		b.Concluded = true
+		assert.False(t, a.Equal(b))
+	})
+}
+
+func FuzzStateBytes(f *testing.F) {
+	f.Add([]byte{1, 2, 3}, uint64(0), false, "stake", []byte{1}, uint8(2), uint8(1))
+	f.Add([]byte{}, uint64(1<<63), true, "a\"< \xff", []byte{0xff, 0xff}, uint8(16), uint8(8))
+	f.Fuzz(func(t *testing.T, id []byte, version uint64, final bool, denom string, amount []byte, numParts, numAssets uint8) {
+		s := newState(id, version, final, denom, uint128(amount), int(numParts%16)+1, int(numAssets%8)+1)
+		assert.Equal(t, encodeReference(t, s), s.Bytes())
+	})
+}
+
+func FuzzParamsBytes(f *testing.F) {
+	f.Add(uint64(60), []byte{1, 2, 3}, []byte{4, 5, 6, 7}, uint8(2))
+	f.Add(uint64(0), []byte{}, []byte{}, uint8(0))
+	f.Fuzz(func(t *testing.T, duration uint64, nonce, parts []byte, numParts uint8) {
+		p := newParams(duration, nonce, parts, int(numParts%16))
+		assert.Equal(t, encodeReference(t, p), p.Bytes())
+	})
+}
+
+func FuzzDisputeQueryResponseEqual(f *testing.F) {
+	f.Add(false, uint64(1), []byte{1}, false, uint64(1), []byte{1})
+	f.Add(false, uint64(1), []byte{1}, true, uint64(1), []byte{1})
+	f.Add(true, uint64(0), []byte{1, 0}, true, uint64(0), []byte{1})
+	f.Fuzz(func(t *testing.T, concludedA bool, timeoutA uint64, amountA []byte, concludedB bool, timeoutB uint64, amountB []byte) {
+		response := func(concluded bool, timeout uint64, amount []byte) binding.DisputeQueryResponse {
+			return binding.DisputeQueryResponse{binding.DisputeQueryResponseMsg{
+				Concluded: concluded,
+				State:     *newState([]byte{1}, 1, false, "stake", uint128(amount), 2, 1),
+				Timeout:   binding.Timestamp(timeout),
+			}}
+		}
+		a, b := response(concludedA, timeoutA, amountA), response(concludedB, timeoutB, amountB)
+		assert.Equal(t, string(encodeReference(t, a)) == string(encodeReference(t, b)), a.Equal(b))
+	})
+}
+
+func BenchmarkStateBytes(b *testing.B) {
+	s := newState(make([]byte, 32), 1<<32, false, "stake", new(big.Int).Lsh(big.NewInt(1), 100), 16, 8)
+	b.Run("direct", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.Bytes()
+		}
+	})
+	b.Run("reference", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			encodeReference(b, s)
+		}
+	})
+}
+
+func BenchmarkParamsBytes(b *testing.B) {
+	p := newParams(3600, make([]byte, 32), make([]byte, 16*33), 16)
+	b.Run("direct", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p.Bytes()
+		}
+	})
+	b.Run("reference", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			encodeReference(b, p)
+		}
+	})
+}
+
+func BenchmarkDisputeQueryResponseEqual(b *testing.B) {
+	r := binding.DisputeQueryResponse{binding.DisputeQueryResponseMsg{
+		State:   *newState(make([]byte, 32), 1<<32, false, "stake", new(big.Int).Lsh(big.NewInt(1), 100), 16, 8),
+		Timeout: 1 << 60,
+	}}
+	b.Run("direct", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			r.Equal(r)
+		}
+	})
+	b.Run("reference", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = string(encodeReference(b, r)) == string(encodeReference(b, r))
+		}
+	})
+}
@@ -26,8 +26,9 @@ type ConcludeExecuteMsg struct {
 }
 
 func NewConcludeExecuteMsg(p channel.Params, s channel.State, sigs []wallet.Sig) ([]byte, error) {
-	msg := ConcludeExecuteMsg{
-		Conclude: makeSignedState(p, s, sigs),
+	signed, err := makeSignedState(p, s, sigs)
+	if err != nil {
+		return nil, err
 	}
-	return json.Marshal(msg)
+	return json.Marshal(ConcludeExecuteMsg{Conclude: signed})
 }
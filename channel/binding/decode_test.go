@@ -0,0 +1,240 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package binding_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unmarshaler is a JSON-decodable binding type.
+type unmarshaler interface {
+	json.Marshaler
+	json.Unmarshaler
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		new     func() unmarshaler
+		valid   []string
+		invalid []string
+	}{
+		{"Uint128", func() unmarshaler { return new(binding.Uint128) },
+			[]string{`"0"`, `"340282366920938463463374607431768211455"`},
+			[]string{`"-1"`, `"340282366920938463463374607431768211456"`, `"1.5"`}},
+		{"Uint64", func() unmarshaler { return new(binding.Uint64) },
+			[]string{`"0"`, `"18446744073709551615"`},
+			[]string{`"-1"`, `"18446744073709551616"`, `"1e3"`}},
+		{"ByteArray", func() unmarshaler { return new(binding.ByteArray) },
+			[]string{`""`, `"AQI="`},
+			[]string{`"AQI"`, `"!"`}},
+	}
+	// Malformed inputs that used to panic and inputs that are not strings.
+	invalid := []string{``, `"`, `1`, `null`, `"""`, `[]`}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, data := range tt.valid {
+				v := tt.new()
+				require.NoError(t, v.UnmarshalJSON([]byte(data)), data)
+				b, err := v.MarshalJSON()
+				require.NoError(t, err)
+				assert.Equal(t, data, string(b))
+			}
+			for _, data := range append(tt.invalid, invalid...) {
+				assert.Error(t, tt.new().UnmarshalJSON([]byte(data)), data)
+			}
+		})
+	}
+}
+
+func TestDecodeDisputeQueryResponse(t *testing.T) {
+	valid := disputeResponse(t, newState(make([]byte, 32), 1, false, "stake", big.NewInt(1), 2, 2))
+	_, err := binding.DecodeDisputeQueryResponse(valid)
+	require.NoError(t, err)
+
+	for name, s := range map[string]*binding.State{
+		"short channel ID": newState(make([]byte, 32), 1, false, "stake", big.NewInt(1), 2, 2),
+		"no participants":  {ChannelID: make([]byte, 32), Balances: binding.Balances{}},
+		"no assets":        {ChannelID: make([]byte, 32), Balances: binding.Balances{{}, {}}},
+		"ragged":           newState(make([]byte, 32), 1, false, "stake", big.NewInt(1), 2, 2),
+		"denoms":           newState(make([]byte, 32), 1, false, "stake", big.NewInt(1), 2, 2),
+	} {
+		switch name {
+		case "short channel ID":
+			s.ChannelID = s.ChannelID[:31]
+		case "ragged":
+			s.Balances[1] = s.Balances[1][:1]
+		case "denoms":
+			s.Balances[1][0].Denom = "other"
+		}
+		_, err := binding.DecodeDisputeQueryResponse(disputeResponse(t, s))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestDecodeWithoutValidation checks that responses are still checked when
+// schema validation is disabled.
+func TestDecodeWithoutValidation(t *testing.T) {
+	skip := binding.DecodeSchemaValidationOpt(false)
+	valid := newState(make([]byte, 32), 1, false, "stake", big.NewInt(1), 2, 2)
+	_, err := binding.DecodeDisputeQueryResponse(disputeResponse(t, valid), skip)
+	require.NoError(t, err)
+
+	for _, data := range []string{
+		`{"concluded":false,"state":null,"timeout":"1"}`,
+		`{"concluded":false,"state":{"balances":[],"channel_id":"","finalized":false,"version":"1"},"timeout":"1"}`,
+		`{"concluded":false,"timeout":1}`,
+	} {
+		_, err := binding.DecodeDisputeQueryResponse([]byte(data), skip)
+		assert.Error(t, err, data)
+	}
+	for _, data := range []string{
+		`[{"denom":"stake","amount":1}]`,
+		`[{"denom":"stake"}]`,
+		`[{"denom":"1nvalid","amount":"1"}]`,
+	} {
+		_, err := binding.DecodeDepositQueryResponse([]byte(data), skip)
+		assert.Error(t, err, data)
+	}
+}
+
+func TestDecodeDepositQueryResponse(t *testing.T) {
+	coins, err := binding.DecodeDepositQueryResponse([]byte(`[{"denom":"stake","amount":"2"},{"denom":"atom","amount":"0"},{"denom":"asset","amount":"1"}]`))
+	require.NoError(t, err)
+	assert.Equal(t, "1asset,2stake", coins.String())
+
+	for _, data := range []string{
+		`[{"denom":"stake","amount":"1"},{"denom":"stake","amount":"2"}]`,
+		`[{"denom":"1nvalid","amount":"1"}]`,
+	} {
+		_, err := binding.DecodeDepositQueryResponse([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+// disputeResponse returns the encoding of a response to a dispute query.
+func disputeResponse(t testing.TB, s *binding.State) []byte {
+	b, err := json.Marshal(binding.DisputeQueryResponseMsg{State: *s, Timeout: 1})
+	require.NoError(t, err)
+	return b
+}
+
+// fuzzUnmarshalJSON checks that decoding does not panic and that decoded
+// values are encoded to a representation that decodes to the same value.
+func fuzzUnmarshalJSON(t *testing.T, data []byte, newValue func() unmarshaler) {
+	v := newValue()
+	if v.UnmarshalJSON(data) != nil {
+		return
+	}
+	b, err := v.MarshalJSON()
+	require.NoError(t, err)
+	_v := newValue()
+	require.NoError(t, _v.UnmarshalJSON(b))
+	_b, err := _v.MarshalJSON()
+	require.NoError(t, err)
+	assert.Equal(t, b, _b)
+}
+
+func FuzzUint128UnmarshalJSON(f *testing.F) {
+	for _, seed := range []string{`"0"`, `"340282366920938463463374607431768211455"`, `"-1"`, `"`, `null`} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzUnmarshalJSON(t, data, func() unmarshaler { return new(binding.Uint128) })
+	})
+}
+
+func FuzzUint64UnmarshalJSON(f *testing.F) {
+	for _, seed := range []string{`"0"`, `"18446744073709551615"`, `"1e3"`, `"`, `null`} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzUnmarshalJSON(t, data, func() unmarshaler { return new(binding.Uint64) })
+	})
+}
+
+func FuzzByteArrayUnmarshalJSON(f *testing.F) {
+	for _, seed := range []string{`""`, `"AQI="`, `"AQI"`, `"`, `null`} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzUnmarshalJSON(t, data, func() unmarshaler { return new(binding.ByteArray) })
+	})
+}
+
+func FuzzDecodeDisputeQueryResponse(f *testing.F) {
+	f.Add(disputeResponse(f, newState(make([]byte, 32), 1, false, "stake", big.NewInt(1), 2, 2)))
+	f.Add([]byte(`{"concluded":false,"state":{"balances":[],"channel_id":"","finalized":false,"version":"0"},"timeout":"0"}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		resp, err := binding.DecodeDisputeQueryResponse(data)
+		if err != nil {
+			return
+		}
+		resp.State.PerunState()
+		assert.True(t, resp.Equal(resp))
+	})
+}
+
+func FuzzDecodeDepositQueryResponse(f *testing.F) {
+	f.Add([]byte(`[{"denom":"stake","amount":"1"}]`))
+	f.Add([]byte(`[{"denom":"stake","amount":"1"},{"denom":"stake","amount":"0"}]`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		coins, err := binding.DecodeDepositQueryResponse(data)
+		if err != nil {
+			return
+		}
+		assert.NoError(t, coins.Validate())
+	})
+}
+
+func FuzzAssetDecode(f *testing.F) {
+	f.Add([]byte{0, 5, 's', 't', 'a', 'k', 'e'})
+	f.Add([]byte{0xff, 0xff, 'a'})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var a binding.Asset
+		if a.Decode(bytes.NewReader(data)) != nil {
+			return
+		}
+		var buf bytes.Buffer
+		require.NoError(t, a.Encode(&buf))
+		assert.Equal(t, data[:buf.Len()], buf.Bytes())
+	})
+}
+
+// BenchmarkDecodeDisputeQueryResponse compares decoding with and without
+// schema validation.
+func BenchmarkDecodeDisputeQueryResponse(b *testing.B) {
+	data := disputeResponse(b, newState(make([]byte, 32), 1<<32, false, "stake", new(big.Int).Lsh(big.NewInt(1), 100), 16, 8))
+	for _, validate := range []bool{true, false} {
+		opt := binding.DecodeSchemaValidationOpt(validate)
+		b.Run(fmt.Sprintf("validate=%t", validate), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := binding.DecodeDisputeQueryResponse(data, opt); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -21,7 +21,6 @@ import (
 
 	"github.com/cosmos/cosmos-sdk/types"
 	"github.com/perun-network/perun-cosmwasm-backend/channel/contract"
-	"github.com/xeipuuv/gojsonschema"
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/wallet"
 )
@@ -49,32 +48,35 @@ type (
 	DepositResponseMsg []Coin
 )
 
-var depositReponseSchema = func() gojsonschema.JSONLoader {
-	schema := contract.DepositResponseSchema
-	return gojsonschema.NewStringLoader(schema)
-}()
+var depositResponseSchema = mustCompileSchema(contract.DepositResponseSchema)
 
 // DecodeDepositQueryResponse decodes a deposit query response from the
 // given byte slice.
-func DecodeDepositQueryResponse(b []byte) (DepositQueryResponse, error) {
-	err := validateMsg(b, depositReponseSchema)
-	if err != nil {
-		return nil, fmt.Errorf("validating: %w", err)
+func DecodeDepositQueryResponse(b []byte, opts ...DecodeOpt) (DepositQueryResponse, error) {
+	if makeDecodeOpts(opts).validate {
+		if err := validateMsg(b, depositResponseSchema); err != nil {
+			return nil, fmt.Errorf("validating: %w", err)
+		}
 	}
 
 	var coins DepositResponseMsg
-	err = json.Unmarshal(b, &coins)
+	err := json.Unmarshal(b, &coins)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshalling: %w", err)
 	}
 
 	coinsList := make([]types.Coin, len(coins))
 	for i, coin := range coins {
-		denom := coin.Denom
-		amount := coin.Amount.Int()
-		coinsList[i] = types.NewCoin(denom, amount)
+		if coin.Amount.val == nil {
+			return nil, fmt.Errorf("missing amount of %q", coin.Denom)
+		}
+		coinsList[i] = types.Coin{Denom: coin.Denom, Amount: coin.Amount.Int()}
+	}
+	_coins, err := newCoins(coinsList)
+	if err != nil {
+		return nil, fmt.Errorf("invalid coins: %w", err)
 	}
-	return types.NewCoins(coinsList...), nil
+	return _coins, nil
 }
 
 type Coin struct {
@@ -101,11 +103,6 @@ func CalcFundingID(ch channel.ID, addr wallet.Address) (FundingID, error) {
 		Part:    addr.Bytes(),
 	}
 
-	b, err := encodeCanonical(f)
-	if err != nil {
-		return nil, fmt.Errorf("encoding: %w", err)
-	}
-
-	h := sha256.Sum256(b)
+	h := sha256.Sum256(encodeCanonical(&f))
 	return h[:], nil
 }
@@ -22,7 +22,6 @@ import (
 
 	"github.com/perun-network/perun-cosmwasm-backend/channel/contract"
 	"github.com/perun-network/perun-cosmwasm-backend/pkg/safecast"
-	"github.com/xeipuuv/gojsonschema"
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/wallet"
 )
@@ -32,10 +31,11 @@ type DisputeExecuteMsg struct {
 }
 
 func NewDisputeExecuteMsg(p channel.Params, s channel.State, sigs []wallet.Sig) ([]byte, error) {
-	msg := DisputeExecuteMsg{
-		Dispute: makeSignedState(p, s, sigs),
+	signed, err := makeSignedState(p, s, sigs)
+	if err != nil {
+		return nil, err
 	}
-	return json.Marshal(msg)
+	return json.Marshal(DisputeExecuteMsg{Dispute: signed})
 }
 
 type SignedState struct {
@@ -44,11 +44,14 @@ type SignedState struct {
 	Sigs   []Sig  `json:"sigs"`
 }
 
-func makeSignedState(p channel.Params, s channel.State, sigs []wallet.Sig) SignedState {
+func makeSignedState(p channel.Params, s channel.State, sigs []wallet.Sig) (SignedState, error) {
 	params := makeParams(&p)
-	state := makeState(&s)
+	state, err := makeState(&s)
+	if err != nil {
+		return SignedState{}, err
+	}
 	_sigs := makeSigs(sigs)
-	return SignedState{params, state, _sigs}
+	return SignedState{params, state, _sigs}, nil
 }
 
 type DisputeQueryMsg struct {
@@ -76,23 +79,24 @@ type (
 	Timestamp = Uint64
 )
 
-var disputeReponseSchema = func() gojsonschema.JSONLoader {
-	schema := contract.DisputeResponseSchema
-	return gojsonschema.NewStringLoader(schema)
-}()
+var disputeResponseSchema = mustCompileSchema(contract.DisputeResponseSchema)
 
 // DecodeDisputeQueryResponse decodes a dispute query response from the given byte slice.
-func DecodeDisputeQueryResponse(b []byte) (DisputeQueryResponse, error) {
-	err := validateMsg(b, disputeReponseSchema)
-	if err != nil {
-		return DisputeQueryResponse{}, fmt.Errorf("validating: %w", err)
+func DecodeDisputeQueryResponse(b []byte, opts ...DecodeOpt) (DisputeQueryResponse, error) {
+	if makeDecodeOpts(opts).validate {
+		if err := validateMsg(b, disputeResponseSchema); err != nil {
+			return DisputeQueryResponse{}, fmt.Errorf("validating: %w", err)
+		}
 	}
 
 	var resp DisputeQueryResponseMsg
-	err = json.Unmarshal(b, &resp)
+	err := json.Unmarshal(b, &resp)
 	if err != nil {
 		return DisputeQueryResponse{}, fmt.Errorf("unmarshalling: %w", err)
 	}
+	if err := resp.State.validate(); err != nil {
+		return DisputeQueryResponse{}, fmt.Errorf("invalid state: %w", err)
+	}
 	return DisputeQueryResponse{resp}, nil
 }
 
@@ -104,15 +108,7 @@ func (r *DisputeQueryResponse) Timeout() time.Time {
 
 // Equal returns whether receiver and argument are equal.
 func (d DisputeQueryResponse) Equal(_d DisputeQueryResponse) bool {
-	b, err := encodeCanonical(d)
-	if err != nil {
-		panic(err)
-	}
-	_b, err := encodeCanonical(_d)
-	if err != nil {
-		panic(err)
-	}
-	return bytes.Equal(b, _b)
+	return bytes.Equal(encodeCanonical(&d), encodeCanonical(&_d))
 }
 
 func makeSigs(sigs []wallet.Sig) []Sig {
@@ -0,0 +1,133 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package binding
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors returned when translating channel objects into contract objects.
+var (
+	ErrInvalidAsset      = errors.New("invalid asset")
+	ErrInvalidAllocation = errors.New("invalid allocation")
+)
+
+// Errors returned by the Perun contract.
+var (
+	ErrUnauthorized                = errors.New("unauthorized")
+	ErrNotConcluded                = errors.New("not concluded")
+	ErrStateFinal                  = errors.New("state final")
+	ErrStateNotFinal               = errors.New("state not final")
+	ErrDenomsMismatch              = errors.New("denoms mismatch")
+	ErrOutcomeOverflow             = errors.New("outcome overflow")
+	ErrInvalidOutcome              = errors.New("invalid outcome")
+	ErrWrongChannelID              = errors.New("wrong channel id")
+	ErrInvalidNumberOfSignatures   = errors.New("invalid number of signatures")
+	ErrWrongNumberOfSignatures     = errors.New("wrong number of signatures")
+	ErrInvalidSignature            = errors.New("invalid signature")
+	ErrInvalidIdentity             = errors.New("invalid identity")
+	ErrDisputeTimeoutNotElapsed    = errors.New("dispute timeout not elapsed")
+	ErrConcludedWithDifferentState = errors.New("concluded with different state")
+	ErrAlreadyConcluded            = errors.New("already concluded")
+	ErrDisputeTimedOut             = errors.New("dispute timed out")
+	ErrVersionTooLow               = errors.New("dispute version too low")
+	ErrUnknownDeposit              = errors.New("unknown deposit")
+	ErrUnknownChannel              = errors.New("unknown channel")
+	ErrUnknownDispute              = errors.New("unknown dispute")
+	ErrInsufficientDeposit         = errors.New("insufficient deposits")
+)
+
+// contractErrors maps the error messages emitted by the contract to the
+// corresponding error values. The messages are matched as substrings because
+// the ledger wraps them differently depending on the client.
+var contractErrors = []struct {
+	msg string
+	err error
+}{
+	{"Unauthorized", ErrUnauthorized},
+	{"Not concluded", ErrNotConcluded},
+	{"State not final", ErrStateNotFinal},
+	{"State final", ErrStateFinal},
+	{"Demons mismatch", ErrDenomsMismatch}, // Sic, as spelled by the contract.
+	{"Outcome overflow", ErrOutcomeOverflow},
+	{"Invalid outcome", ErrInvalidOutcome},
+	{"Wrong channel id", ErrWrongChannelID},
+	{"Invalid number of signatures", ErrInvalidNumberOfSignatures},
+	{"Wrong number of signatures", ErrWrongNumberOfSignatures},
+	{"Wrong signature", ErrInvalidSignature},
+	{"Invalid signature", ErrInvalidSignature},
+	{"Invalid identity", ErrInvalidIdentity},
+	{"Concluded too early", ErrDisputeTimeoutNotElapsed},
+	{"Concluded with different state", ErrConcludedWithDifferentState},
+	{"Already concluded", ErrAlreadyConcluded},
+	{"Dispute timed out", ErrDisputeTimedOut},
+	{"Dispute version too low", ErrVersionTooLow},
+	{"Unknown deposit", ErrUnknownDeposit},
+	{"Unknown channel", ErrUnknownChannel},
+	{"Unknown dispute", ErrUnknownDispute},
+	{"Insufficient deposits", ErrInsufficientDeposit},
+}
+
+// ContractError is an error that was returned by the Perun contract.
+//
+// It matches the corresponding error value via errors.Is and unwraps to the
+// original error returned by the client.
+type ContractError struct {
+	kind  error
+	cause error
+}
+
+// Error returns the error message of the underlying client error.
+func (e *ContractError) Error() string {
+	return e.cause.Error()
+}
+
+// Kind returns the error value describing the contract error.
+func (e *ContractError) Kind() error {
+	return e.kind
+}
+
+// Is returns whether the contract error is of the given kind.
+func (e *ContractError) Is(target error) bool {
+	return e.kind == target
+}
+
+// Unwrap returns the original error returned by the client.
+func (e *ContractError) Unwrap() error {
+	return e.cause
+}
+
+// ParseContractError translates an error returned by a client while
+// interacting with the Perun contract into a ContractError. If the error does
+// not originate from the contract, it is returned unchanged.
+func ParseContractError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var cErr *ContractError
+	if errors.As(err, &cErr) {
+		return err
+	}
+
+	msg := err.Error()
+	for _, e := range contractErrors {
+		if strings.Contains(msg, e.msg) {
+			return &ContractError{kind: e.err, cause: err}
+		}
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package binding_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseContractError(t *testing.T) {
+	tests := []struct {
+		err  error
+		kind error
+	}{
+		// Simulation client.
+		{errors.New("Unknown dispute: query wasm contract failed"), binding.ErrUnknownDispute},
+		{fmt.Errorf("handling message: %w", errors.New("Dispute version too low: execute wasm contract failed")), binding.ErrVersionTooLow},
+		// Node client.
+		{errors.New("rpc error: code = Unknown desc = Concluded too early: execute wasm contract failed"), binding.ErrDisputeTimeoutNotElapsed},
+		{errors.New("Invalid signature: Invalid recovery parameter: execute wasm contract failed"), binding.ErrInvalidSignature},
+		{errors.New("Wrong signature: execute wasm contract failed"), binding.ErrInvalidSignature},
+		{errors.New("Already concluded: execute wasm contract failed"), binding.ErrAlreadyConcluded},
+		{errors.New("Insufficient deposits: execute wasm contract failed"), binding.ErrInsufficientDeposit},
+	}
+
+	for _, tt := range tests {
+		err := binding.ParseContractError(tt.err)
+		assert.ErrorIs(t, err, tt.kind, tt.err.Error())
+		assert.ErrorIs(t, err, tt.err, "unwrap")
+		assert.Equal(t, tt.err.Error(), err.Error(), "message")
+
+		var cErr *binding.ContractError
+		assert.True(t, errors.As(err, &cErr), "as")
+		assert.Equal(t, cErr, binding.ParseContractError(err), "idempotent")
+	}
+
+	other := errors.New("connection refused")
+	assert.Equal(t, other, binding.ParseContractError(other), "non-contract error")
+	assert.Nil(t, binding.ParseContractError(nil), "nil")
+}
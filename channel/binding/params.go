@@ -47,11 +47,7 @@ func makeParticipants(parts []wallet.Address) []OffIdentity {
 
 // Bytes returns a canonical byte representation of the object.
 func (p *Params) Bytes() []byte {
-	b, err := encodeCanonical(p)
-	if err != nil {
-		panic(err)
-	}
-	return b
+	return encodeCanonical(p)
 }
 
 func NewParams(p *channel.Params) *Params {
@@ -0,0 +1,24 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package schema contains the message types of the Perun contract as
+// generated from its JSON schemas in channel/contract/schema.
+//
+// The types describe the wire format only. Package binding builds the
+// messages from go-perun types and is checked against these types in its
+// tests, so that a schema change that is not reflected in the binding fails
+// the build instead of being rejected by the contract at runtime.
+package schema
+
+//go:generate go run ./gen -in ../../contract/schema -out types.go
@@ -0,0 +1,380 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const header = `//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Code generated by gen from the contract's JSON schemas. DO NOT EDIT.
+
+`
+
+const (
+	refPrefix   = "#/definitions/"
+	commentCols = 76
+)
+
+// schema is the subset of JSON schema emitted by cosmwasm-schema. Decoding
+// fails on any other keyword so that the generator does not silently ignore
+// constraints it cannot express.
+type schema struct {
+	Schema               string             `json:"$schema"`
+	Title                string             `json:"title"`
+	Description          string             `json:"description"`
+	Type                 string             `json:"type"`
+	Ref                  string             `json:"$ref"`
+	AllOf                []*schema          `json:"allOf"`
+	AnyOf                []*schema          `json:"anyOf"`
+	Items                *schema            `json:"items"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Definitions          map[string]*schema `json:"definitions"`
+}
+
+type generator struct {
+	defs  map[string]*schema
+	names map[string]bool
+	decls []string
+}
+
+// generate reads all JSON schemas in dir and returns the formatted Go code
+// of the corresponding types.
+func generate(dir, pkg string) ([]byte, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no schemas in %s", dir)
+	}
+	sort.Strings(files)
+
+	g := &generator{defs: make(map[string]*schema), names: make(map[string]bool)}
+	roots := make([]*schema, len(files))
+	for i, f := range files {
+		s, err := readSchema(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f, err)
+		}
+		if s.Title == "" {
+			return nil, fmt.Errorf("%s: missing title", f)
+		}
+		if err := g.addDefinitions(s.Definitions); err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		roots[i] = s
+	}
+
+	for _, s := range roots {
+		if err := g.decl(s.Title, fmt.Sprintf("%s is the contract's %s.", s.Title, s.Title), s); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Title, err)
+		}
+	}
+	names := make([]string, 0, len(g.defs))
+	for name := range g.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := g.decl(name, fmt.Sprintf("%s is the contract's %s type.", name, name), g.defs[name]); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	fmt.Fprintf(&buf, "package %s\n", pkg)
+	for _, d := range g.decls {
+		buf.WriteString("\n")
+		buf.WriteString(d)
+	}
+	return format.Source(buf.Bytes())
+}
+
+func readSchema(file string) (*schema, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var s schema
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// addDefinitions adds the definitions of a schema file. Definitions with the
+// same name in different files must be identical.
+func (g *generator) addDefinitions(defs map[string]*schema) error {
+	for name, def := range defs {
+		if prev, ok := g.defs[name]; ok {
+			a, _ := json.Marshal(prev)
+			b, _ := json.Marshal(def)
+			if !bytes.Equal(a, b) {
+				return fmt.Errorf("conflicting definitions of %s", name)
+			}
+			continue
+		}
+		g.defs[name] = def
+	}
+	return nil
+}
+
+// decl generates the declaration of the named type and of all anonymous
+// types it contains.
+func (g *generator) decl(name, doc string, s *schema) error {
+	if g.names[name] {
+		return fmt.Errorf("duplicate type name %s", name)
+	}
+	g.names[name] = true
+
+	// Reserve the slot so that nested declarations follow this one.
+	idx := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var buf bytes.Buffer
+	writeComment(&buf, "", doc, s.Description)
+	switch {
+	case len(s.AnyOf) > 0:
+		if err := g.union(&buf, name, s); err != nil {
+			return err
+		}
+	case isObject(s):
+		body, err := g.structType(name, s)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "type %s %s\n", name, body)
+	default:
+		typ, err := g.goType(name, s)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "type %s %s\n", name, typ)
+	}
+	g.decls[idx] = buf.String()
+	return nil
+}
+
+// goType returns the Go type of s. Anonymous objects are declared as a new
+// type with the given name.
+func (g *generator) goType(name string, s *schema) (string, error) {
+	switch {
+	case s.Ref != "":
+		ref := strings.TrimPrefix(s.Ref, refPrefix)
+		if ref == s.Ref || g.defs[ref] == nil {
+			return "", fmt.Errorf("unresolved reference %q", s.Ref)
+		}
+		return ref, nil
+	case len(s.AllOf) == 1 && s.Type == "" && s.Properties == nil:
+		return g.goType(name, s.AllOf[0])
+	case len(s.AllOf) > 1 || len(s.AnyOf) > 0:
+		return "", fmt.Errorf("unsupported combination in %s", name)
+	case isObject(s):
+		if err := g.decl(name, fmt.Sprintf("%s is an anonymous object of the contract.", name), s); err != nil {
+			return "", err
+		}
+		return name, nil
+	}
+
+	switch s.Type {
+	case "string":
+		return "string", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		if s.Items == nil {
+			return "", fmt.Errorf("array without items in %s", name)
+		}
+		elem, err := g.goType(name+"Item", s.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elem, nil
+	}
+	return "", fmt.Errorf("unsupported type %q in %s", s.Type, name)
+}
+
+func (g *generator) structType(name string, s *schema) (string, error) {
+	if len(s.Properties) == 0 {
+		return "struct{}", nil
+	}
+	props := make([]string, 0, len(s.Properties))
+	for p := range s.Properties {
+		props = append(props, p)
+	}
+	sort.Strings(props)
+
+	var buf bytes.Buffer
+	buf.WriteString("struct {\n")
+	for _, p := range props {
+		ps := s.Properties[p]
+		field := goName(p)
+		typ, err := g.goType(name+field, ps)
+		if err != nil {
+			return "", err
+		}
+		tag := p
+		if !contains(s.Required, p) {
+			tag += ",omitempty"
+			if !strings.HasPrefix(typ, "[]") {
+				typ = "*" + typ
+			}
+		}
+		writeComment(&buf, "\t", "", ps.Description)
+		fmt.Fprintf(&buf, "\t%s %s `json:\"%s\"`\n", field, typ, tag)
+	}
+	for _, r := range s.Required {
+		if s.Properties[r] == nil {
+			return "", fmt.Errorf("required property %q is not defined", r)
+		}
+	}
+	buf.WriteString("}")
+	return buf.String(), nil
+}
+
+// union generates a message that is one of several single-key objects, which
+// is how cosmwasm-schema encodes Rust enums. Each variant becomes an optional
+// field with a constructor that sets it.
+func (g *generator) union(buf *bytes.Buffer, name string, s *schema) error {
+	type variant struct{ key, field, typ, doc string }
+	variants := make([]variant, len(s.AnyOf))
+	for i, v := range s.AnyOf {
+		if !isObject(v) || len(v.Properties) != 1 || len(v.Required) != 1 || v.Properties[v.Required[0]] == nil {
+			return fmt.Errorf("variant %d of %s is not a single-key object", i, name)
+		}
+		key := v.Required[0]
+		field := goName(key)
+		typ, prop := name+field, v.Properties[key]
+		var err error
+		if isObject(prop) {
+			err = g.decl(typ, fmt.Sprintf("%s is the payload of the %s variant of %s.", typ, key, name), prop)
+		} else {
+			typ, err = g.goType(typ, prop)
+		}
+		if err != nil {
+			return err
+		}
+		variants[i] = variant{key: key, field: field, typ: typ, doc: v.Description}
+	}
+
+	fmt.Fprintf(buf, "//\n// Exactly one of the fields must be set.\ntype %s struct {\n", name)
+	for _, v := range variants {
+		writeComment(buf, "\t", "", v.doc)
+		fmt.Fprintf(buf, "\t%s *%s `json:\"%s,omitempty\"`\n", v.field, v.typ, v.key)
+	}
+	buf.WriteString("}\n")
+	for _, v := range variants {
+		ctor := "New" + name + v.field
+		fmt.Fprintf(buf, "\n// %s returns %s %s with the %s variant set.\n", ctor, article(name), name, v.key)
+		fmt.Fprintf(buf, "func %s(v %s) %s {\n\treturn %s{%s: &v}\n}\n", ctor, v.typ, name, name, v.field)
+	}
+	return nil
+}
+
+func article(word string) string {
+	if strings.ContainsRune("AEIOU", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+func isObject(s *schema) bool {
+	return s.Type == "object" || (s.Type == "" && s.Properties != nil)
+}
+
+// writeComment writes the first line followed by the first paragraph of the
+// schema description, wrapped to commentCols.
+func writeComment(buf *bytes.Buffer, indent, first, desc string) {
+	para := strings.Join(strings.Fields(strings.SplitN(desc, "\n\n", 2)[0]), " ")
+	var lines []string
+	if first != "" {
+		lines = append(lines, first)
+		if para != "" {
+			lines = append(lines, "")
+		}
+	}
+	line := ""
+	for _, w := range strings.Fields(para) {
+		if line != "" && len(line)+1+len(w) > commentCols {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += w
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	for _, l := range lines {
+		if l == "" {
+			fmt.Fprintf(buf, "%s//\n", indent)
+		} else {
+			fmt.Fprintf(buf, "%s// %s\n", indent, l)
+		}
+	}
+}
+
+// goName converts a snake_case JSON key into an exported Go identifier.
+func goName(key string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(key, "_") {
+		if part == "" {
+			continue
+		}
+		if part == "id" {
+			b.WriteString("ID")
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerated checks that the generated types are up to date with the
+// schemas of the contract.
+func TestGenerated(t *testing.T) {
+	code, err := generate("../../../contract/schema", "schema")
+	require.NoError(t, err)
+	current, err := os.ReadFile("../types.go")
+	require.NoError(t, err)
+	assert.Equal(t, string(current), string(code), "types.go is outdated, run go generate ./channel/binding/schema")
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	tests := []struct {
+		name, schema string
+	}{
+		{"unknown keyword", `{"title": "T", "type": "integer", "format": "uint64"}`},
+		{"unsupported type", `{"title": "T", "type": "integer"}`},
+		{"unresolved reference", `{"title": "T", "$ref": "#/definitions/Missing"}`},
+		{"missing title", `{"type": "string"}`},
+		{"undefined required", `{"title": "T", "type": "object", "required": ["a"], "properties": {"b": {"type": "string"}}}`},
+		{"multi-key variant", `{"title": "T", "anyOf": [{"type": "object", "required": ["a", "b"], "properties": {"a": {"type": "string"}, "b": {"type": "string"}}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "t.json"), []byte(tt.schema), 0600))
+			_, err := generate(dir, "schema")
+			assert.Error(t, err)
+		})
+	}
+}
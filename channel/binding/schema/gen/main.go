@@ -0,0 +1,52 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Command gen generates Go types from the JSON schemas of the Perun contract.
+//
+// It supports the subset of JSON schema that cosmwasm-schema emits for the
+// contract: named definitions, references, objects, arrays, strings, booleans
+// and messages that are a choice of single-key objects.
+//
+// Usage:
+//
+//	go run ./gen -in <schema dir> -out <file> [-pkg <package>]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	in := flag.String("in", "", "directory containing the JSON schemas")
+	out := flag.String("out", "", "output file")
+	pkg := flag.String("pkg", "schema", "package name of the generated code")
+	flag.Parse()
+
+	if *in == "" || *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	code, err := generate(*in, *pkg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gen:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, code, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "gen:", err)
+		os.Exit(1)
+	}
+}
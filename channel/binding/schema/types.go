@@ -0,0 +1,209 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Code generated by gen from the contract's JSON schemas. DO NOT EDIT.
+
+package schema
+
+// DepositResponse is the contract's DepositResponse.
+type DepositResponse []Coin
+
+// DisputeResponse is the contract's DisputeResponse.
+type DisputeResponse Dispute
+
+// ExecuteMsg is the contract's ExecuteMsg.
+//
+// Message to call functions on the [crate::contract].
+//
+// Exactly one of the fields must be set.
+type ExecuteMsg struct {
+	// Deposits funds into a channel for a specific [FundingId].
+	Deposit *WrappedBinary `json:"deposit,omitempty"`
+	// Disputes a channel in case of a dishonest participant.
+	Dispute *ExecuteMsgDispute `json:"dispute,omitempty"`
+	// Concludes a channel.
+	Conclude *ExecuteMsgConclude `json:"conclude,omitempty"`
+	// Withdraws funds from a concluded channel.
+	Withdraw *ExecuteMsgWithdraw `json:"withdraw,omitempty"`
+}
+
+// NewExecuteMsgDeposit returns an ExecuteMsg with the deposit variant set.
+func NewExecuteMsgDeposit(v WrappedBinary) ExecuteMsg {
+	return ExecuteMsg{Deposit: &v}
+}
+
+// NewExecuteMsgDispute returns an ExecuteMsg with the dispute variant set.
+func NewExecuteMsgDispute(v ExecuteMsgDispute) ExecuteMsg {
+	return ExecuteMsg{Dispute: &v}
+}
+
+// NewExecuteMsgConclude returns an ExecuteMsg with the conclude variant set.
+func NewExecuteMsgConclude(v ExecuteMsgConclude) ExecuteMsg {
+	return ExecuteMsg{Conclude: &v}
+}
+
+// NewExecuteMsgWithdraw returns an ExecuteMsg with the withdraw variant set.
+func NewExecuteMsgWithdraw(v ExecuteMsgWithdraw) ExecuteMsg {
+	return ExecuteMsg{Withdraw: &v}
+}
+
+// ExecuteMsgDispute is the payload of the dispute variant of ExecuteMsg.
+type ExecuteMsgDispute struct {
+	Params Params `json:"params"`
+	Sigs   []Sig  `json:"sigs"`
+	State  State  `json:"state"`
+}
+
+// ExecuteMsgConclude is the payload of the conclude variant of ExecuteMsg.
+type ExecuteMsgConclude struct {
+	Params Params `json:"params"`
+	Sigs   []Sig  `json:"sigs"`
+	State  State  `json:"state"`
+}
+
+// ExecuteMsgWithdraw is the payload of the withdraw variant of ExecuteMsg.
+type ExecuteMsgWithdraw struct {
+	Sig        Sig        `json:"sig"`
+	Withdrawal Withdrawal `json:"withdrawal"`
+}
+
+// InitMsg is the contract's InitMsg.
+//
+// Message to initialize the [crate::contract].
+type InitMsg struct{}
+
+// QueryMsg is the contract's QueryMsg.
+//
+// Message to query the state of the [crate::contract].
+//
+// Exactly one of the fields must be set.
+type QueryMsg struct {
+	Deposit *WrappedBinary `json:"deposit,omitempty"`
+	Dispute *WrappedBinary `json:"dispute,omitempty"`
+}
+
+// NewQueryMsgDeposit returns a QueryMsg with the deposit variant set.
+func NewQueryMsgDeposit(v WrappedBinary) QueryMsg {
+	return QueryMsg{Deposit: &v}
+}
+
+// NewQueryMsgDispute returns a QueryMsg with the dispute variant set.
+func NewQueryMsgDispute(v WrappedBinary) QueryMsg {
+	return QueryMsg{Dispute: &v}
+}
+
+// Addr is the contract's Addr type.
+//
+// A human readable address.
+type Addr string
+
+// Binary is the contract's Binary type.
+//
+// Binary is a wrapper around Vec<u8> to add base64 de/serialization with
+// serde. It also adds some helper methods to help encode inline.
+type Binary string
+
+// Coin is the contract's Coin type.
+type Coin struct {
+	Amount Uint128 `json:"amount"`
+	Denom  string  `json:"denom"`
+}
+
+// Dispute is the contract's Dispute type.
+//
+// Stores an on-chain dispute of a channel. Can be advanced with a higher
+// version via `Dispute` as long as the timeout did not run out.
+type Dispute struct {
+	// Indicates whether the dispute has been concluded.
+	Concluded bool `json:"concluded"`
+	// The state of the disputed channel.
+	State State `json:"state"`
+	// Timeout of the dispute.
+	Timeout Timestamp `json:"timeout"`
+}
+
+// NativeBalance is the contract's NativeBalance type.
+type NativeBalance []Coin
+
+// OffIdentity is the contract's OffIdentity type.
+//
+// Off-Chain identity of a participant.
+type OffIdentity WrappedBinary
+
+// Params is the contract's Params type.
+//
+// Fixed parameters of a channel.
+type Params struct {
+	// Challenge duration of the channel.
+	DisputeDuration Uint64 `json:"dispute_duration"`
+	// Nonce to make these Params unique. Should be picked randomly.
+	Nonce Binary `json:"nonce"`
+	// Participants of the channel.
+	Participants []OffIdentity `json:"participants"`
+}
+
+// Sig is the contract's Sig type.
+//
+// Cryptographic signature.
+type Sig WrappedBinary
+
+// State is the contract's State type.
+//
+// Off-Chain state of a channel.
+type State struct {
+	// Balance of each participant in the channel.
+	Balances []NativeBalance `json:"balances"`
+	// Unique channel ID.
+	ChannelID WrappedBinary `json:"channel_id"`
+	// Whether or not this state is final.
+	Finalized bool `json:"finalized"`
+	// Version of the state.
+	Version Uint64 `json:"version"`
+}
+
+// Timestamp is the contract's Timestamp type.
+//
+// A point in time in nanosecond precision.
+type Timestamp Uint64
+
+// Uint128 is the contract's Uint128 type.
+//
+// A thin wrapper around u128 that is using strings for JSON encoding/decoding,
+// such that the full u128 range can be used for clients that convert JSON
+// numbers to floats, like JavaScript and jq.
+type Uint128 string
+
+// Uint64 is the contract's Uint64 type.
+//
+// A thin wrapper around u64 that is using strings for JSON encoding/decoding,
+// such that the full u64 range can be used for clients that convert JSON
+// numbers to floats, like JavaScript and jq.
+type Uint64 string
+
+// Withdrawal is the contract's Withdrawal type.
+//
+// Withdrawal authorization for on-chain funds.
+type Withdrawal struct {
+	// Channel from with to withdraw.
+	ChannelID WrappedBinary `json:"channel_id"`
+	// Off-chain participant to debit.
+	Part OffIdentity `json:"part"`
+	// On-Chain Account to credited.
+	Receiver Addr `json:"receiver"`
+}
+
+// WrappedBinary is the contract's WrappedBinary type.
+//
+// WrappedBinary is a wrapper around Binary that enables usage as a map key.
+type WrappedBinary Binary
@@ -0,0 +1,160 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package binding_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// shape describes the JSON encoding of a type: the keys of objects, whether
+// they are optional, and the kinds of their values.
+func shape(t *testing.T, typ reflect.Type) string {
+	if typ.Implements(marshalerType) {
+		// The custom encodings of the binding are all JSON strings.
+		b, err := json.Marshal(reflect.Zero(typ).Interface())
+		require.NoError(t, err)
+		require.True(t, len(b) > 0 && b[0] == '"', "%v does not encode as string: %s", typ, b)
+		return "string"
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Ptr:
+		return shape(t, typ.Elem())
+	case reflect.Slice:
+		return "[" + shape(t, typ.Elem()) + "]"
+	case reflect.Struct:
+		fields := make([]string, 0, typ.NumField())
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name, opts := f.Name, ""
+			if tag, ok := f.Tag.Lookup("json"); ok {
+				parts := strings.SplitN(tag, ",", 2)
+				if parts[0] != "" {
+					name = parts[0]
+				}
+				if len(parts) > 1 {
+					opts = parts[1]
+				}
+			}
+			if strings.Contains(opts, "omitempty") {
+				name += "?"
+			}
+			fields = append(fields, fmt.Sprintf("%s:%s", name, shape(t, f.Type)))
+		}
+		sort.Strings(fields)
+		return "{" + strings.Join(fields, ",") + "}"
+	}
+	t.Fatalf("unsupported type %v", typ)
+	return ""
+}
+
+// variant returns the shape of the message that sets the variant with the
+// given key of a generated union.
+func variant(t *testing.T, union reflect.Type, key string) string {
+	for i := 0; i < union.NumField(); i++ {
+		f := union.Field(i)
+		if strings.SplitN(f.Tag.Get("json"), ",", 2)[0] == key {
+			return "{" + key + ":" + shape(t, f.Type) + "}"
+		}
+	}
+	t.Fatalf("%v has no variant %q", union, key)
+	return ""
+}
+
+// TestSchemaDrift checks that the hand-written messages of the binding encode
+// like the types generated from the contract's schemas.
+func TestSchemaDrift(t *testing.T) {
+	execute := reflect.TypeOf(schema.ExecuteMsg{})
+	query := reflect.TypeOf(schema.QueryMsg{})
+	tests := []struct {
+		name      string
+		binding   interface{}
+		generated string
+	}{
+		{"deposit", binding.DepositExecuteMsg{}, variant(t, execute, "deposit")},
+		{"dispute", binding.DisputeExecuteMsg{}, variant(t, execute, "dispute")},
+		{"conclude", binding.ConcludeExecuteMsg{}, variant(t, execute, "conclude")},
+		{"withdraw", binding.WithdrawExecuteMsg{}, variant(t, execute, "withdraw")},
+		{"deposit query", binding.DepositExecuteMsg{}, variant(t, query, "deposit")},
+		{"dispute query", binding.DisputeQueryMsg{}, variant(t, query, "dispute")},
+		{"deposit response", binding.DepositResponseMsg{}, shape(t, reflect.TypeOf(schema.DepositResponse{}))},
+		{"dispute response", binding.DisputeQueryResponseMsg{}, shape(t, reflect.TypeOf(schema.DisputeResponse{}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.generated, shape(t, reflect.TypeOf(tt.binding)))
+		})
+	}
+}
+
+// TestSchemaRoundTrip checks that messages of the binding decode into the
+// generated types without loss.
+func TestSchemaRoundTrip(t *testing.T) {
+	state := *newState([]byte{1, 2, 3}, 7, false, "stake", big.NewInt(42), 3, 2)
+	params := *newParams(60, []byte{4, 5}, []byte{6, 7, 8}, 3)
+	signed := binding.SignedState{Params: params, State: state, Sigs: []binding.Sig{{9}, {10}, {11}}}
+	coin := binding.Balance(state.Balances[0])
+
+	tests := []struct {
+		name      string
+		binding   interface{}
+		generated interface{}
+	}{
+		{"deposit", binding.DepositExecuteMsg{Deposit: []byte{1}}, &schema.ExecuteMsg{}},
+		{"dispute", binding.DisputeExecuteMsg{Dispute: signed}, &schema.ExecuteMsg{}},
+		{"conclude", binding.ConcludeExecuteMsg{Conclude: signed}, &schema.ExecuteMsg{}},
+		{"withdraw", binding.WithdrawExecuteMsg{Withdraw: binding.SignedWithdrawal{
+			Withdrawal: binding.Withdrawal{ChannelId: []byte{1}, Part: []byte{2}, Receiver: "cosmos1receiver"},
+			Sig:        []byte{3},
+		}}, &schema.ExecuteMsg{}},
+		{"dispute query", binding.DisputeQueryMsg{Dispute: []byte{1}}, &schema.QueryMsg{}},
+		{"deposit response", binding.DepositResponseMsg(coin), &schema.DepositResponse{}},
+		{"dispute response", binding.DisputeQueryResponseMsg{State: state, Timeout: 1234}, &schema.DisputeResponse{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.binding)
+			require.NoError(t, err)
+
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.DisallowUnknownFields()
+			require.NoError(t, dec.Decode(tt.generated))
+			_b, err := json.Marshal(tt.generated)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(b), string(_b))
+		})
+	}
+}
@@ -15,6 +15,9 @@
 package binding
 
 import (
+	"fmt"
+	"math"
+
 	perun "github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel"
 	"github.com/perun-network/perun-cosmwasm-backend/pkg/safecast"
 	"perun.network/go-perun/channel"
@@ -29,48 +32,73 @@ type State struct {
 
 type ChannelID = Hash
 
-func makeState(s *channel.State) State {
+func makeState(s *channel.State) (State, error) {
+	bals, err := makeBalances(s.Allocation)
+	if err != nil {
+		return State{}, err
+	}
 	return State{
 		ChannelID: s.ID[:],
 		Version:   makeUint64(s.Version),
-		Balances:  makeBalances(s.Allocation),
+		Balances:  bals,
 		Finalized: s.IsFinal,
-	}
+	}, nil
 }
 
-func makeBalances(a channel.Allocation) []Balance {
+func makeBalances(a channel.Allocation) ([]Balance, error) {
 	if len(a.Locked) > 0 {
-		panic("locked")
+		return nil, fmt.Errorf("%w: locked funds", ErrInvalidAllocation)
 	}
 
 	assets := a.Assets
 	bals := a.Balances
-	if len(bals) == 0 {
-		panic("empty balances")
+	if len(bals) == 0 || len(bals[0]) == 0 {
+		return nil, fmt.Errorf("%w: empty balances", ErrInvalidAllocation)
+	}
+	if len(assets) != len(bals) {
+		return nil, fmt.Errorf("%w: %d assets but balances for %d", ErrInvalidAllocation, len(assets), len(bals))
 	}
 	numParts := len(bals[0])
-	_bals := make([]Balance, numParts)
+	if numParts > math.MaxUint16+1 {
+		return nil, fmt.Errorf("%w: %d participants", ErrInvalidAllocation, numParts)
+	}
+	for i, assetBals := range bals {
+		if len(assetBals) != numParts {
+			return nil, fmt.Errorf("%w: %d balances for asset %d instead of %d", ErrInvalidAllocation, len(assetBals), i, numParts)
+		}
+		for j, bal := range assetBals {
+			if bal == nil {
+				return nil, fmt.Errorf("%w: missing balance of asset %d for participant %d", ErrInvalidAllocation, i, j)
+			}
+		}
+	}
 
+	_bals := make([]Balance, numParts)
 	for i := range _bals {
 		partIdx := safecast.Uint16FromInt(i)
 		partBals := perun.Balances(bals).ForPart(partIdx)
-		_bals[i] = makeBalance(assets, partBals)
+		var err error
+		if _bals[i], err = makeBalance(assets, partBals); err != nil {
+			return nil, err
+		}
 	}
-	return _bals
+	return _bals, nil
 }
 
-func makeBalance(assets []channel.Asset, bals []channel.Bal) Balance {
-	if len(assets) != len(bals) {
-		panic("length mismatch")
-	}
-
+func makeBalance(assets []channel.Asset, bals []channel.Bal) (Balance, error) {
 	_bals := make(Balance, len(bals))
 	for i, bal := range bals {
-		denom := makeDenom(assets[i])
-		amount := makeUint128(bal)
+		denom, err := makeDenom(assets[i])
+		if err != nil {
+			return nil, err
+		}
+		amount, err := newUint128(bal)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAllocation, err)
+		}
 		_bals[i] = makeCoin(denom, amount)
 	}
-	return _bals
+	return _bals, nil
 }
 
 func (s *State) PerunState() *channel.State {
@@ -86,6 +114,32 @@ func (s *State) PerunState() *channel.State {
 	}
 }
 
+// validate checks that the state can be converted to a Perun state: the
+// channel ID has the right length, and all participants hold amounts of the
+// same assets in the same order.
+func (s *State) validate() error {
+	if len(s.ChannelID) != len(channel.ID{}) {
+		return fmt.Errorf("channel ID has length %d", len(s.ChannelID))
+	}
+	if len(s.Balances) == 0 || len(s.Balances[0]) == 0 {
+		return fmt.Errorf("empty balances")
+	}
+	for p, bal := range s.Balances {
+		if len(bal) != len(s.Balances[0]) {
+			return fmt.Errorf("participant %d has %d assets instead of %d", p, len(bal), len(s.Balances[0]))
+		}
+		for a, coin := range bal {
+			if coin.Denom != s.Balances[0][a].Denom {
+				return fmt.Errorf("participant %d has asset %q instead of %q at index %d", p, coin.Denom, s.Balances[0][a].Denom, a)
+			}
+			if coin.Amount.val == nil {
+				return fmt.Errorf("participant %d has no amount of %q", p, coin.Denom)
+			}
+		}
+	}
+	return nil
+}
+
 type (
 	Balances []Balance
 	Balance  []Coin
@@ -114,14 +168,16 @@ func (b Balances) perunAllocation() channel.Allocation {
 
 // Bytes returns a canonical byte representation of the object.
 func (s *State) Bytes() []byte {
-	b, err := encodeCanonical(s)
-	if err != nil {
-		panic(err)
-	}
-	return b
+	return encodeCanonical(s)
 }
 
-func NewState(s *channel.State) *State {
-	_s := makeState(s)
-	return &_s
+// NewState translates a state into a contract state. It returns an error if
+// the state cannot be represented in the contract, so that states received
+// from peers can be checked before signing them.
+func NewState(s *channel.State) (*State, error) {
+	_s, err := makeState(s)
+	if err != nil {
+		return nil, err
+	}
+	return &_s, nil
 }
@@ -0,0 +1,49 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package binding
+
+import (
+	"encoding/binary"
+
+	"perun.network/go-perun/channel"
+)
+
+// Storage namespaces of the Perun contract. The values stored under these
+// namespaces are encoded like the corresponding query responses.
+const (
+	depositsNamespace = "deposits"
+	disputesNamespace = "register"
+)
+
+// DepositKey returns the key under which the contract stores the deposit for
+// the given funding ID.
+func DepositKey(fID FundingID) []byte {
+	return storageKey(depositsNamespace, fID)
+}
+
+// DisputeKey returns the key under which the contract stores the dispute for
+// the given channel.
+func DisputeKey(id channel.ID) []byte {
+	return storageKey(disputesNamespace, id[:])
+}
+
+// storageKey returns the key of an entry of a map with the given namespace,
+// which is the length-prefixed namespace followed by the entry key.
+func storageKey(namespace string, key []byte) []byte {
+	b := make([]byte, 2, 2+len(namespace)+len(key))
+	binary.BigEndian.PutUint16(b, uint16(len(namespace)))
+	b = append(b, namespace...)
+	return append(b, key...)
+}
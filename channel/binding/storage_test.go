@@ -0,0 +1,32 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package binding_test
+
+import (
+	"testing"
+
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/stretchr/testify/assert"
+	"perun.network/go-perun/channel"
+)
+
+func TestStorageKeys(t *testing.T) {
+	var id channel.ID
+	id[0], id[31] = 1, 2
+	assert.Equal(t, append([]byte("\x00\x08register"), id[:]...), binding.DisputeKey(id))
+
+	fID := binding.FundingID{3, 4}
+	assert.Equal(t, []byte("\x00\x08deposits\x03\x04"), binding.DepositKey(fID))
+}
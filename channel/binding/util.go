@@ -22,7 +22,6 @@ import (
 	"strconv"
 
 	"github.com/cosmos/cosmos-sdk/types"
-	"github.com/gowebpki/jcs"
 	"github.com/xeipuuv/gojsonschema"
 )
 
@@ -41,14 +40,13 @@ type Uint128 struct {
 	val *big.Int
 }
 
-func makeUint128(i *big.Int) Uint128 {
+// newUint128 returns a copy of the given integer as Uint128, or an error if
+// it is out of range.
+func newUint128(i *big.Int) (Uint128, error) {
 	if i.Sign() < 0 || i.Cmp(uint128Max) >= 0 {
-		panic(fmt.Sprintf("must be in [0, 2^128]: %v", i))
-	}
-	_i := new(big.Int).Set(i)
-	return Uint128{
-		val: _i,
+		return Uint128{}, fmt.Errorf("must be in [0, 2^128): %v", i)
 	}
+	return Uint128{val: new(big.Int).Set(i)}, nil
 }
 
 func (i Uint128) Int() types.Int {
@@ -61,20 +59,56 @@ func (i Uint128) MarshalJSON() ([]byte, error) {
 }
 
 func (i *Uint128) UnmarshalJSON(data []byte) error {
-	_data := data[1 : len(data)-1] // Strip quotes.
-	_i, ok := new(big.Int).SetString(string(_data), 10)
+	_data, err := unmarshalString(data)
+	if err != nil {
+		return err
+	}
+	_i, ok := new(big.Int).SetString(_data, 10)
 	if !ok {
-		return fmt.Errorf("failed to parse: %v", data)
+		return fmt.Errorf("failed to parse: %q", _data)
 	}
-	*i = makeUint128(_i)
-	return nil
+	*i, err = newUint128(_i)
+	return err
 }
 
 type Sig = ByteArray
 
-func validateMsg(msg []byte, schema gojsonschema.JSONLoader) error {
-	_msg := gojsonschema.NewBytesLoader(msg)
-	result, err := gojsonschema.Validate(schema, _msg)
+// mustCompileSchema compiles one of the embedded schemas of the contract.
+func mustCompileSchema(schema string) *gojsonschema.Schema {
+	s, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		panic(fmt.Sprintf("compiling schema: %v", err))
+	}
+	return s
+}
+
+// DecodeOpt is an option for decoding contract responses.
+type DecodeOpt func(*decodeOpts)
+
+type decodeOpts struct {
+	validate bool
+}
+
+// DecodeSchemaValidationOpt sets whether responses are validated against the
+// schema of the contract before decoding. Validation is enabled by default.
+// Decoding still rejects malformed responses without it, but the errors are
+// less descriptive.
+func DecodeSchemaValidationOpt(enabled bool) DecodeOpt {
+	return func(o *decodeOpts) {
+		o.validate = enabled
+	}
+}
+
+func makeDecodeOpts(opts []DecodeOpt) decodeOpts {
+	o := decodeOpts{validate: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func validateMsg(msg []byte, schema *gojsonschema.Schema) error {
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(msg))
 	if err != nil {
 		return err
 	}
@@ -101,22 +135,16 @@ func (i Uint64) MarshalJSON() ([]byte, error) {
 }
 
 func (i *Uint64) UnmarshalJSON(data []byte) error {
-	_data := data[1 : len(data)-1] // Strip quotes.
-	_i, err := strconv.ParseUint(string(_data), 10, 64)
+	_data, err := unmarshalString(data)
 	if err != nil {
 		return err
 	}
-	*i = makeUint64(_i)
-	return nil
-}
-
-// encodeCanonical creates a canonical encoding of an object.
-func encodeCanonical(o interface{}) ([]byte, error) {
-	b, err := json.Marshal(o)
+	_i, err := strconv.ParseUint(_data, 10, 64)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return jcs.Transform(b)
+	*i = makeUint64(_i)
+	return nil
 }
 
 // ByteArray represents a byte array that is JSON-encodable.
@@ -128,8 +156,11 @@ func (a ByteArray) MarshalJSON() ([]byte, error) {
 }
 
 func (a *ByteArray) UnmarshalJSON(data []byte) error {
-	_data := data[1 : len(data)-1] // Strip quotes.
-	_a, err := base64.StdEncoding.DecodeString(string(_data))
+	_data, err := unmarshalString(data)
+	if err != nil {
+		return err
+	}
+	_a, err := base64.StdEncoding.DecodeString(_data)
 	if err != nil {
 		return err
 	}
@@ -138,3 +169,16 @@ func (a *ByteArray) UnmarshalJSON(data []byte) error {
 }
 
 type Hash = ByteArray
+
+// unmarshalString decodes a JSON string. Unlike json.Unmarshal, it returns an
+// error for null, as the types encoded as strings are never optional.
+func unmarshalString(data []byte) (string, error) {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return "", err
+	}
+	if s == nil {
+		return "", fmt.Errorf("expected string, got null")
+	}
+	return *s, nil
+}
@@ -0,0 +1,110 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package binding
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/types"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+// Version is a version of the message encoding of the contract. The messages
+// of this package implement the encoding of the version Version1.
+type Version uint
+
+// Versions of the message encoding.
+const (
+	Version1 Version = 1 // Messages, storage layout and canonical encoding of perun-cosmwasm-contract 0.1.
+)
+
+// Encoder encodes the messages and storage keys of a version of the message
+// encoding and decodes the query responses.
+type Encoder interface {
+	// Version returns the version of the message encoding.
+	Version() Version
+
+	NewDepositExecuteMsg(fID FundingID) ([]byte, error)
+	NewDisputeExecuteMsg(p channel.Params, s channel.State, sigs []wallet.Sig) ([]byte, error)
+	NewConcludeExecuteMsg(p channel.Params, s channel.State, sigs []wallet.Sig) ([]byte, error)
+	NewWithdrawMsgExecute(ch channel.ID, part wallet.Address, receiver types.Address, sig wallet.Sig) ([]byte, error)
+	// WithdrawalBytes returns the encoding of a withdrawal that is signed
+	// by the participant.
+	WithdrawalBytes(ch channel.ID, part wallet.Address, receiver types.Address) ([]byte, error)
+
+	NewDepositQueryMsg(fID FundingID) ([]byte, error)
+	NewDisputeQueryMsg(ch channel.ID) ([]byte, error)
+	DecodeDepositQueryResponse(b []byte, opts ...DecodeOpt) (DepositQueryResponse, error)
+	DecodeDisputeQueryResponse(b []byte, opts ...DecodeOpt) (DisputeQueryResponse, error)
+
+	DepositKey(fID FundingID) []byte
+	DisputeKey(ch channel.ID) []byte
+}
+
+// NewEncoder returns the encoder for the given version of the message
+// encoding. It returns an error if the version is unknown.
+func NewEncoder(v Version) (Encoder, error) {
+	switch v {
+	case Version1:
+		return encoderV1{}, nil
+	}
+	return nil, fmt.Errorf("unknown message encoding version: %d", v)
+}
+
+// encoderV1 implements Version1 with the messages of this package.
+type encoderV1 struct{}
+
+func (encoderV1) Version() Version { return Version1 }
+
+func (encoderV1) NewDepositExecuteMsg(fID FundingID) ([]byte, error) {
+	return NewDepositExecuteMsg(fID)
+}
+
+func (encoderV1) NewDisputeExecuteMsg(p channel.Params, s channel.State, sigs []wallet.Sig) ([]byte, error) {
+	return NewDisputeExecuteMsg(p, s, sigs)
+}
+
+func (encoderV1) NewConcludeExecuteMsg(p channel.Params, s channel.State, sigs []wallet.Sig) ([]byte, error) {
+	return NewConcludeExecuteMsg(p, s, sigs)
+}
+
+func (encoderV1) NewWithdrawMsgExecute(ch channel.ID, part wallet.Address, receiver types.Address, sig wallet.Sig) ([]byte, error) {
+	return NewWithdrawMsgExecute(ch, part, receiver, sig)
+}
+
+func (encoderV1) WithdrawalBytes(ch channel.ID, part wallet.Address, receiver types.Address) ([]byte, error) {
+	return NewWithdrawal(ch, part, receiver).Bytes()
+}
+
+func (encoderV1) NewDepositQueryMsg(fID FundingID) ([]byte, error) {
+	return NewDepositQueryMsg(fID)
+}
+
+func (encoderV1) NewDisputeQueryMsg(ch channel.ID) ([]byte, error) {
+	return NewDisputeQueryMsg(ch)
+}
+
+func (encoderV1) DecodeDepositQueryResponse(b []byte, opts ...DecodeOpt) (DepositQueryResponse, error) {
+	return DecodeDepositQueryResponse(b, opts...)
+}
+
+func (encoderV1) DecodeDisputeQueryResponse(b []byte, opts ...DecodeOpt) (DisputeQueryResponse, error) {
+	return DecodeDisputeQueryResponse(b, opts...)
+}
+
+func (encoderV1) DepositKey(fID FundingID) []byte { return DepositKey(fID) }
+
+func (encoderV1) DisputeKey(ch channel.ID) []byte { return DisputeKey(ch) }
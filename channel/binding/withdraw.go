@@ -58,12 +58,8 @@ func makeWithdrawal(p channel.ID, part wallet.Address, receiver types.Address) W
 }
 
 // Bytes returns a canonical byte representation of the object.
-func (w *Withdrawal) Bytes() []byte {
-	b, err := json.Marshal(w)
-	if err != nil {
-		panic(err)
-	}
-	return b
+func (w *Withdrawal) Bytes() ([]byte, error) {
+	return json.Marshal(w)
 }
 
 func NewWithdrawal(p channel.ID, part wallet.Address, receiver types.Address) *Withdrawal {
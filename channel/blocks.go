@@ -0,0 +1,193 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/log"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/metrics"
+	tmtypes "github.com/tendermint/tendermint/proto/tendermint/types"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BlockWatcher caches the latest block header of a client and notifies
+// waiters about new blocks.
+//
+// A single BlockWatcher should be shared by all components using the same
+// client so that the latest block is only queried once per polling interval.
+// The ledger is only polled while there are waiters.
+type BlockWatcher struct {
+	client  client.Client
+	polling time.Duration
+	retry   RetryPolicy
+	log     log.Logger
+	metrics *metrics.Metrics
+	tracer  trace.Tracer // Used by timeouts bound to this watcher.
+
+	mu       sync.Mutex
+	header   *tmtypes.Header
+	updated  time.Time
+	newBlock chan struct{} // Closed and replaced when a new block is observed.
+	waiters  int
+	stop     chan struct{}
+}
+
+// NewBlockWatcher creates a new block watcher that queries the latest block
+// of the given client at most once per polling interval.
+func NewBlockWatcher(c client.Client, polling time.Duration) *BlockWatcher {
+	return newBlockWatcher(c, polling, DefaultRetryPolicy(), log.Default())
+}
+
+func newBlockWatcher(c client.Client, polling time.Duration, retry RetryPolicy, l log.Logger) *BlockWatcher {
+	return &BlockWatcher{
+		client:   c,
+		polling:  polling,
+		retry:    retry,
+		log:      l,
+		tracer:   defaultTracer(),
+		newBlock: make(chan struct{}),
+	}
+}
+
+// Latest returns the header of the latest block. The cached header is
+// returned if it has been updated within the last polling interval. Failed
+// queries are retried according to the retry policy.
+func (w *BlockWatcher) Latest(ctx context.Context) (tmtypes.Header, error) {
+	w.mu.Lock()
+	if w.header != nil && time.Since(w.updated) < w.polling {
+		h := *w.header
+		w.mu.Unlock()
+		return h, nil
+	}
+	w.mu.Unlock()
+
+	var h tmtypes.Header
+	err := w.retry.do(ctx, func() (bool, error) {
+		var err error
+		h, err = w.update(ctx)
+		return w.retry.classify(err) != ErrorPermanent, err
+	})
+	return h, err
+}
+
+// headerAt returns the header of the block at the given height. Failed
+// queries are retried according to the retry policy.
+func (w *BlockWatcher) headerAt(ctx context.Context, height int64) (tmtypes.Header, error) {
+	var h tmtypes.Header
+	err := w.retry.do(ctx, func() (bool, error) {
+		resp, err := w.client.GetBlockByHeight(ctx, &tmservice.GetBlockByHeightRequest{Height: height})
+		if err != nil {
+			return w.retry.classify(err) != ErrorPermanent, err
+		}
+		h = resp.Block.Header
+		return false, nil
+	})
+	return h, err
+}
+
+// update queries the latest block and updates the cache. Waiters are
+// notified if the block is new.
+func (w *BlockWatcher) update(ctx context.Context) (tmtypes.Header, error) {
+	resp, err := w.client.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	if err != nil {
+		return tmtypes.Header{}, err
+	}
+	h := resp.Block.Header
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.header == nil || h.Height > w.header.Height {
+		w.header = &h
+		close(w.newBlock)
+		w.newBlock = make(chan struct{})
+	}
+	w.updated = time.Now()
+	return *w.header, nil
+}
+
+// Wait blocks until the given condition holds for the latest block header.
+// If the context is canceled, Wait returns immediately with the context's
+// error.
+func (w *BlockWatcher) Wait(ctx context.Context, cond func(tmtypes.Header) bool) error {
+	w.addWaiter()
+	defer w.removeWaiter()
+
+	for {
+		w.mu.Lock()
+		h, newBlock := w.header, w.newBlock
+		w.mu.Unlock()
+
+		if h != nil && cond(*h) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-newBlock:
+		}
+	}
+}
+
+func (w *BlockWatcher) addWaiter() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.waiters++
+	if w.waiters == 1 {
+		w.stop = make(chan struct{})
+		go w.poll(w.stop)
+	}
+}
+
+func (w *BlockWatcher) removeWaiter() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.waiters--
+	if w.waiters == 0 {
+		close(w.stop)
+	}
+}
+
+// poll queries the latest block on every polling interval until stop is
+// closed.
+func (w *BlockWatcher) poll(stop chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	for {
+		w.metrics.Poll(metrics.ComponentBlocks)
+		_, err := w.update(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.log.WithError(err).Warnf("Getting latest block failed")
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(w.polling):
+		}
+	}
+}
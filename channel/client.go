@@ -2,57 +2,254 @@ package channel
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
 	"github.com/cosmos/cosmos-sdk/types"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
 	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/proof"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/log"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type contractClient struct {
 	client   client.Client
 	contract client.ContractInstance
 	acc      types.AccAddress
+	retry    RetryPolicy
+	verified *proof.Querier // If set, contract state is read with verified queries.
+	log      log.Logger
+	metrics  *metrics.Metrics // If nil, no metrics are recorded.
+	tracer   trace.Tracer
+	enc      binding.Encoder // The message encoding of the contract's version.
+	validate bool            // If set, messages are validated against the contract's schemas.
 }
 
-func newContractClient(client client.Client, contract client.ContractInstance, acc types.AccAddress) *contractClient {
+// newContractClient creates a client for the given contract after checking
+// that the contract is compatible with the backend. Messages are encoded as
+// required by the version of the contract.
+func newContractClient(ctx context.Context, client client.Client, contract client.ContractInstance, acc types.AccAddress) (*contractClient, error) {
+	version, err := CheckContract(ctx, client, contract)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := binding.NewEncoder(version.Binding)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIncompatibleContract, err)
+	}
 	return &contractClient{
 		client:   client,
 		contract: contract,
 		acc:      acc,
-	}
+		retry:    DefaultRetryPolicy(),
+		log:      log.Default(),
+		tracer:   defaultTracer(),
+		enc:      enc,
+		validate: true,
+	}, nil
 }
 
+// decodeOpt returns the option for decoding contract responses.
+func (c *contractClient) decodeOpt() binding.DecodeOpt {
+	return binding.DecodeSchemaValidationOpt(c.validate)
+}
+
+// landedFunc returns whether a transaction has been applied on the ledger.
+type landedFunc func(ctx context.Context) (bool, error)
+
 // Account returns the account used for interacting with the network.
 func (c *contractClient) Account() types.AccAddress {
 	return c.acc
 }
 
-// Query submits a contract query.
+// Query submits a contract query. Errors returned by the contract are
+// translated into binding.ContractError. Failed queries are retried according
+// to the retry policy.
 func (c *contractClient) Query(ctx context.Context, msg []byte) (*wtypes.QuerySmartContractStateResponse, error) {
-	err := c.contract.ValidateQueryMsg(msg)
-	if err != nil {
-		return nil, err
+	if c.validate {
+		if err := c.contract.ValidateQueryMsg(msg); err != nil {
+			return nil, err
+		}
 	}
 
 	req := &wtypes.QuerySmartContractStateRequest{
 		Address:   c.contract.Address(),
 		QueryData: msg,
 	}
-	return c.client.SmartContractState(ctx, req)
+	var (
+		resp *wtypes.QuerySmartContractStateResponse
+		err  error
+	)
+	err = c.retry.do(ctx, func() (bool, error) {
+		resp, err = c.client.SmartContractState(ctx, req)
+		err = binding.ParseContractError(err)
+		return c.retry.classify(err) != ErrorPermanent, err
+	})
+	return resp, err
 }
 
-// Execute executes a contract function.
+// Execute executes a contract function. Errors returned by the contract are
+// translated into binding.ContractError. Failed transactions are only retried
+// if it is certain that they have not been applied.
 func (c *contractClient) Execute(ctx context.Context, msg []byte, funds types.Coins) (*wtypes.MsgExecuteContractResponse, error) {
-	err := c.contract.ValidateExecuteMsg(msg)
-	if err != nil {
-		return nil, err
+	return c.execute(ctx, c.log, msg, funds, nil)
+}
+
+// execute executes a contract function. If the outcome of an attempt is
+// uncertain, landed is used to check whether the transaction has been
+// applied. If it has been applied, execute returns a nil response and no
+// error. If landed is nil, attempts with an uncertain outcome are not
+// retried. As the check may be answered by a node that has not caught up yet,
+// it is repeated if a retry is rejected by the contract. Attempts are logged
+// to l.
+func (c *contractClient) execute(ctx context.Context, l log.Logger, msg []byte, funds types.Coins, landed landedFunc) (*wtypes.MsgExecuteContractResponse, error) {
+	return c.executeFrom(ctx, l, c.client, c.acc, msg, funds, landed)
+}
+
+// executeFrom is like execute but sends the transaction from account acc
+// with client cl, which must be able to sign for the account.
+func (c *contractClient) executeFrom(ctx context.Context, l log.Logger, cl client.Client, acc types.AccAddress, msg []byte, funds types.Coins, landed landedFunc) (*wtypes.MsgExecuteContractResponse, error) {
+	if c.validate {
+		if err := c.contract.ValidateExecuteMsg(msg); err != nil {
+			return nil, err
+		}
 	}
 
 	_msg := &wtypes.MsgExecuteContract{
-		Sender:   c.acc.String(),
+		Sender:   acc.String(),
 		Contract: c.contract.Address(),
 		Msg:      msg,
 		Funds:    funds,
 	}
-	return c.client.ExecuteContract(ctx, _msg)
+	var (
+		resp      *wtypes.MsgExecuteContractResponse
+		uncertain bool
+		err       error
+	)
+	err = c.retry.do(ctx, func() (bool, error) {
+		resp, err = c.broadcast(ctx, l, cl, _msg)
+		switch c.retry.classify(err) {
+		case ErrorTransient:
+			l.WithError(err).Warnf("Executing contract failed")
+			return true, err
+		case ErrorUncertain:
+			l.WithError(err).Warnf("Executing contract has uncertain outcome")
+			if landed == nil {
+				return false, err
+			}
+			uncertain = true
+			ok, checkErr := c.confirm(ctx, landed)
+			if checkErr != nil {
+				return false, fmt.Errorf("checking transaction: %v: %w", checkErr, err)
+			} else if ok {
+				l.Debugf("Transaction with uncertain outcome has been applied")
+				resp = nil
+				return false, nil
+			}
+			return true, err
+		}
+		return false, err
+	})
+
+	var cErr *binding.ContractError
+	if uncertain && errors.As(err, &cErr) {
+		if ok, checkErr := c.confirm(ctx, landed); checkErr == nil && ok {
+			l.Debugf("Transaction with uncertain outcome has been applied")
+			return nil, nil
+		}
+	}
+	return resp, err
+}
+
+// broadcast sends the message with client cl in a single attempt. Errors
+// returned by the contract are translated into binding.ContractError.
+func (c *contractClient) broadcast(ctx context.Context, l log.Logger, cl client.Client, msg *wtypes.MsgExecuteContract) (_ *wtypes.MsgExecuteContractResponse, err error) {
+	ctx, span := c.tracer.Start(ctx, SpanBroadcast)
+	defer func() { endSpan(span, err) }()
+
+	var md metadata.MD
+	resp, err := cl.ExecuteContract(ctx, msg, grpc.Header(&md))
+	err = binding.ParseContractError(err)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := txFields(md)
+	for k, v := range fields {
+		span.SetAttributes(attribute.String(k, v.(string)))
+	}
+	l.WithFields(fields).Debugf("Executed contract")
+	return resp, nil
+}
+
+// confirm checks whether a transaction with an uncertain outcome has been
+// applied.
+func (c *contractClient) confirm(ctx context.Context, landed landedFunc) (ok bool, err error) {
+	ctx, span := c.tracer.Start(ctx, SpanConfirm)
+	defer func() {
+		span.SetAttributes(attribute.Bool(landedKey, ok))
+		endSpan(span, err)
+	}()
+	return landed(ctx)
+}
+
+// txFields returns the transaction hash and block height reported in the
+// given response headers.
+func txFields(md metadata.MD) log.Fields {
+	fields := make(log.Fields)
+	if v := md.Get(client.TxHashHeader); len(v) > 0 {
+		fields[log.TxHashKey] = v[0]
+	}
+	if v := md.Get(grpctypes.GRPCBlockHeightHeader); len(v) > 0 {
+		fields[log.HeightKey] = v[0]
+	}
+	return fields
+}
+
+// queryVerified reads the raw contract state for the given key with a
+// verified query. It returns nil if the key is not set. Failed queries are
+// retried according to the retry policy.
+func (c *contractClient) queryVerified(ctx context.Context, key []byte) ([]byte, error) {
+	var value []byte
+	err := c.retry.do(ctx, func() (bool, error) {
+		var err error
+		value, err = c.verified.RawContractState(ctx, c.contract.Address(), key)
+		return c.retry.classify(err) != ErrorPermanent, err
+	})
+	return value, err
+}
+
+// queryDepositByID queries the current deposit state for the given funding ID.
+// If nothing has been deposited, an empty deposit is returned.
+func (c *contractClient) queryDepositByID(ctx context.Context, fID binding.FundingID) (binding.DepositQueryResponse, error) {
+	if c.verified != nil {
+		b, err := c.queryVerified(ctx, c.enc.DepositKey(fID))
+		if err != nil {
+			return nil, err
+		} else if b == nil {
+			return types.NewCoins(), nil
+		}
+		return c.enc.DecodeDepositQueryResponse(b, c.decodeOpt())
+	}
+
+	msg, err := c.enc.NewDepositQueryMsg(fID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Query(ctx, msg)
+	if errors.Is(err, binding.ErrUnknownDeposit) || errors.Is(err, binding.ErrUnknownChannel) {
+		return types.NewCoins(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return c.enc.DecodeDepositQueryResponse(resp.Data, c.decodeOpt())
 }
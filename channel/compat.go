@@ -0,0 +1,98 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/contract"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+)
+
+// ErrIncompatibleContract is returned if the contract on the ledger is not
+// supported by the backend.
+var ErrIncompatibleContract = errors.New("incompatible contract")
+
+// ContractVersion is a version of the Perun contract.
+type ContractVersion struct {
+	Name     string          // Name of the version.
+	CodeHash []byte          // SHA-256 hash of the Wasm code.
+	Binding  binding.Version // Message encoding used by the version.
+}
+
+// SupportedContracts are the versions of the Perun contract that the backend
+// can interact with. The funder and adjudicator encode their messages as
+// required by the version of their contract.
+var SupportedContracts = []ContractVersion{
+	{Name: "0.1", CodeHash: codeHash(contract.Code), Binding: binding.Version1},
+}
+
+func codeHash(code []byte) []byte {
+	h := sha256.Sum256(code)
+	return h[:]
+}
+
+// CheckContract returns the version of the contract instance on the ledger,
+// identified by the hash of its code. It returns ErrIncompatibleContract if
+// the instance does not run the code with the ID of the given instance or
+// if the code is not one of SupportedContracts.
+func CheckContract(ctx context.Context, c client.Client, contract client.ContractInstance) (ContractVersion, error) {
+	info, err := c.ContractInfo(ctx, &wtypes.QueryContractInfoRequest{Address: contract.Address()})
+	if err != nil {
+		return ContractVersion{}, fmt.Errorf("querying contract info: %w", err)
+	}
+	if info.CodeID != contract.ID() {
+		return ContractVersion{}, fmt.Errorf("%w: contract %s runs code %d instead of %d",
+			ErrIncompatibleContract, contract.Address(), info.CodeID, contract.ID())
+	}
+
+	code, err := codeInfo(ctx, c, info.CodeID)
+	if err != nil {
+		return ContractVersion{}, fmt.Errorf("querying code info: %w", err)
+	}
+	hash := code.DataHash
+	for _, v := range SupportedContracts {
+		if bytes.Equal(v.CodeHash, hash) {
+			return v, nil
+		}
+	}
+	return ContractVersion{}, fmt.Errorf("%w: code %d of contract %s has unknown hash %X",
+		ErrIncompatibleContract, info.CodeID, contract.Address(), []byte(hash))
+}
+
+// codeInfo returns the metadata of the code with the given ID. Unlike the
+// Code query, it does not download the Wasm code.
+func codeInfo(ctx context.Context, c client.Client, id uint64) (wtypes.CodeInfoResponse, error) {
+	// Codes are listed in the order of their IDs, so the page starting at the
+	// ID contains the code if it exists.
+	resp, err := c.Codes(ctx, &wtypes.QueryCodesRequest{
+		Pagination: &query.PageRequest{Key: types.Uint64ToBigEndian(id), Limit: 1},
+	})
+	if err != nil {
+		return wtypes.CodeInfoResponse{}, err
+	}
+	if len(resp.CodeInfos) == 0 || resp.CodeInfos[0].CodeID != id {
+		return wtypes.CodeInfoResponse{}, fmt.Errorf("code %d not found", id)
+	}
+	return resp.CodeInfos[0], nil
+}
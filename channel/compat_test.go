@@ -0,0 +1,101 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	chtest "github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/simulation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestCheckContract(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	c, contract := chtest.NewTestClientWithContract(ctx, t)
+
+	t.Run("supported", func(t *testing.T) {
+		v, err := bchannel.CheckContract(ctx, c, contract)
+		require.NoError(t, err)
+		assert.Equal(t, "0.1", v.Name)
+
+		_, err = bchannel.NewFunder(ctx, c, contract, c.Account())
+		assert.NoError(t, err)
+		_, err = bchannel.NewAdjudicator(ctx, c, contract, c.Account())
+		assert.NoError(t, err)
+	})
+
+	t.Run("metadata only", func(t *testing.T) {
+		v, err := bchannel.CheckContract(ctx, noCodeClient{c}, contract)
+		require.NoError(t, err)
+		assert.Equal(t, "0.1", v.Name)
+	})
+
+	t.Run("wrong code id", func(t *testing.T) {
+		stored := client.NewStoredContract(contract, contract.ID()+1)
+		wrong := client.NewContractInstance(stored, contract.Address())
+		_, err := bchannel.CheckContract(ctx, c, wrong)
+		assert.ErrorIs(t, err, bchannel.ErrIncompatibleContract)
+	})
+
+	t.Run("encoders", func(t *testing.T) {
+		for _, v := range bchannel.SupportedContracts {
+			enc, err := binding.NewEncoder(v.Binding)
+			require.NoError(t, err, "version %s", v.Name)
+			assert.Equal(t, v.Binding, enc.Version(), "version %s", v.Name)
+		}
+	})
+
+	t.Run("unknown encoding", func(t *testing.T) {
+		supported := bchannel.SupportedContracts
+		bchannel.SupportedContracts = []bchannel.ContractVersion{supported[0]}
+		bchannel.SupportedContracts[0].Binding = binding.Version1 + 1
+		defer func() { bchannel.SupportedContracts = supported }()
+
+		_, err := bchannel.NewFunder(ctx, c, contract, c.Account())
+		assert.ErrorIs(t, err, bchannel.ErrIncompatibleContract)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		supported := bchannel.SupportedContracts
+		bchannel.SupportedContracts = nil
+		defer func() { bchannel.SupportedContracts = supported }()
+
+		_, err := bchannel.CheckContract(ctx, c, contract)
+		assert.ErrorIs(t, err, bchannel.ErrIncompatibleContract)
+		_, err = bchannel.NewFunder(ctx, c, contract, c.Account())
+		assert.ErrorIs(t, err, bchannel.ErrIncompatibleContract)
+		_, err = bchannel.NewAdjudicator(ctx, c, contract, c.Account())
+		assert.ErrorIs(t, err, bchannel.ErrIncompatibleContract)
+	})
+}
+
+// noCodeClient is a client that refuses to download Wasm code.
+type noCodeClient struct {
+	*simulation.Client
+}
+
+func (noCodeClient) Code(context.Context, *wtypes.QueryCodeRequest, ...grpc.CallOption) (*wtypes.QueryCodeResponse, error) {
+	return nil, errors.New("code download not allowed")
+}
@@ -0,0 +1,110 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"perun.network/go-perun/channel"
+	pclient "perun.network/go-perun/client"
+)
+
+// Transaction types used for reporting timed out transactions.
+const (
+	txTypeDeposit  = "deposit"
+	txTypeDispute  = "dispute"
+	txTypeConclude = "conclude"
+	txTypeWithdraw = "withdraw"
+)
+
+// perunError is a go-perun error that retains the error it was created from.
+//
+// The go-perun error is exposed via Cause and Unwrap so that go-perun's
+// type checks apply, while errors.Is and errors.As also match the original
+// error.
+type perunError struct {
+	err   error
+	cause error
+}
+
+func (e *perunError) Error() string {
+	return fmt.Sprintf("%v: %v", e.err, e.cause)
+}
+
+// Cause returns the go-perun error.
+func (e *perunError) Cause() error {
+	return e.err
+}
+
+// Unwrap returns the go-perun error.
+func (e *perunError) Unwrap() error {
+	return e.err
+}
+
+// Is returns whether the original error matches the target.
+func (e *perunError) Is(target error) bool {
+	return errors.Is(e.cause, target)
+}
+
+// As finds the first error in the original error's chain that matches the
+// target.
+func (e *perunError) As(target interface{}) bool {
+	return errors.As(e.cause, target)
+}
+
+// makePerunError translates an error that occurred while sending a
+// transaction of the given type for the specified channel into the
+// corresponding go-perun error type. If there is no corresponding type, the
+// error is returned unchanged.
+func makePerunError(err error, id channel.ID, txType string) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, binding.ErrVersionTooLow),
+		errors.Is(err, binding.ErrAlreadyConcluded),
+		errors.Is(err, binding.ErrConcludedWithDifferentState):
+		pErr := channel.NewStateTransitionError(id, "conflicting on-chain state")
+		return &perunError{err: pErr, cause: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		pErr := pclient.NewTxTimedoutError(txType, "", err.Error())
+		return &perunError{err: pErr, cause: err}
+	case isChainNotReachableError(err):
+		pErr := pclient.NewChainNotReachableError(err)
+		return &perunError{err: pErr, cause: err}
+	}
+	return err
+}
+
+// isChainNotReachableError returns whether the error indicates that the node
+// could not be reached.
+func isChainNotReachableError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return false
+	}
+	return grpcErr.GRPCStatus().Code() == codes.Unavailable
+}
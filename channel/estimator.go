@@ -0,0 +1,363 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/types"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	perun "github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel"
+	bwallet "github.com/perun-network/perun-cosmwasm-backend/wallet"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+// DefaultGasAdjustment is the default factor by which simulated gas is
+// multiplied to account for differences between simulation and execution.
+const DefaultGasAdjustment = 1.3
+
+// Cost is the gas used by an operation and the resulting fee.
+type Cost struct {
+	Gas uint64
+	Fee types.Coin
+}
+
+// Add returns the sum of both costs. The fees must have the same denom.
+func (c Cost) Add(d Cost) Cost {
+	return Cost{Gas: c.Gas + d.Gas, Fee: c.Fee.Add(d.Fee)}
+}
+
+// Estimate is the estimated cost of the on-chain operations of a channel for
+// one participant. Each operation is a separate transaction, so each cost
+// includes the fixed costs of a transaction.
+type Estimate struct {
+	Deposit  Cost // Depositing the funds of the participant, zero if it has none.
+	Dispute  Cost // Registering a state.
+	Conclude Cost // Concluding the channel.
+	Withdraw Cost // Withdrawing the funds of the participant.
+}
+
+// Open returns the cost for the participant to open the channel.
+func (e Estimate) Open() Cost {
+	return e.Deposit
+}
+
+// Close returns the cost for the participant to close the channel
+// collaboratively, assuming that it concludes the channel.
+func (e Estimate) Close() Cost {
+	return e.Conclude.Add(e.Withdraw)
+}
+
+// ForceClose returns the cost for the participant to close the channel via
+// a dispute, assuming that it registers the state and concludes the channel.
+func (e Estimate) ForceClose() Cost {
+	return e.Dispute.Add(e.Close())
+}
+
+// Estimator estimates the cost of the on-chain operations of channels by
+// simulating them against the current state of the ledger.
+type Estimator struct {
+	sim           client.Simulator
+	contract      client.ContractInstance
+	acc           types.AccAddress
+	gasPrice      types.DecCoin
+	gasAdjustment float64
+}
+
+// EstimatorOpt is an optional parameter for NewEstimator.
+type EstimatorOpt func(*Estimator)
+
+// EstimatorGasAdjustmentOpt sets the factor by which simulated gas is
+// multiplied.
+func EstimatorGasAdjustmentOpt(f float64) EstimatorOpt {
+	return func(e *Estimator) {
+		e.gasAdjustment = f
+	}
+}
+
+// NewEstimator creates a new estimator that simulates the operations with the
+// given account and computes the fees in the denom of the given gas price.
+func NewEstimator(sim client.Simulator, contract client.ContractInstance, acc types.AccAddress, gasPrice types.DecCoin, opts ...EstimatorOpt) *Estimator {
+	e := &Estimator{
+		sim:           sim,
+		contract:      contract,
+		acc:           acc,
+		gasPrice:      gasPrice,
+		gasAdjustment: DefaultGasAdjustment,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Estimate estimates the cost of the on-chain operations of a channel with
+// the given parameters and state for the participant with the given index.
+//
+// The operations are simulated on a copy of the channel whose participants
+// are replaced by temporary accounts, so that the states can be signed. In
+// the copy, only the participant holds funds, which are deposited from the
+// estimator's account. The account must therefore hold the participant's
+// funds, as returned by EstimationFunds. As the other participants hold
+// nothing in the copy, the cost of disputes and conclusions may be slightly
+// lower than for the channel itself.
+func (e *Estimator) Estimate(ctx context.Context, params *channel.Params, state *channel.State, idx channel.Index) (Estimate, error) {
+	if int(idx) >= len(params.Parts) {
+		return Estimate{}, fmt.Errorf("invalid participant index: %d", idx)
+	}
+	ch, err := newEstimationChannel(params, state, idx, 0)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("creating channel: %w", err)
+	}
+	deposits, err := ch.deposits(e.acc)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("creating deposit: %w", err)
+	}
+	dispute, err := ch.dispute()
+	if err != nil {
+		return Estimate{}, fmt.Errorf("creating dispute: %w", err)
+	}
+	conclude, err := ch.conclude()
+	if err != nil {
+		return Estimate{}, fmt.Errorf("creating conclusion: %w", err)
+	}
+	withdrawal, err := ch.withdrawal(e.acc)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("creating withdrawal: %w", err)
+	}
+	overhead, err := e.txOverhead(ctx, params, state, idx)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("simulating transaction overhead: %w", err)
+	}
+
+	// The dispute and the collaborative close are simulated separately, as
+	// the channel cannot be concluded before the dispute timeout.
+	n := len(deposits)
+	disputeGas, err := e.simulateTxs(ctx, overhead, append(deposits[:n:n], dispute))
+	if err != nil {
+		return Estimate{}, fmt.Errorf("simulating dispute: %w", err)
+	}
+	closeGas, err := e.simulateTxs(ctx, overhead, append(deposits[:n:n], conclude, withdrawal))
+	if err != nil {
+		return Estimate{}, fmt.Errorf("simulating close: %w", err)
+	}
+
+	est := Estimate{
+		Deposit:  e.cost(0),
+		Dispute:  e.cost(disputeGas[n]),
+		Conclude: e.cost(closeGas[n]),
+		Withdraw: e.cost(closeGas[n+1]),
+	}
+	if n > 0 {
+		est.Deposit = e.cost(closeGas[0])
+	}
+	return est, nil
+}
+
+// EstimationFunds returns the funds that the estimator's account must hold
+// for estimating the cost of a channel with the given state for the
+// participant with the given index, which are the participant's funds.
+func EstimationFunds(state *channel.State, idx channel.Index) (types.Coins, error) {
+	return binding.MakeCoins(state.Assets, perun.Balances(state.Balances).ForPart(idx))
+}
+
+// txOverhead returns the fixed gas of a transaction that the simulator
+// includes in the gas of the first simulated message. It is measured by
+// simulating the disputes of two channels of the same shape, which use the
+// same gas apart from the fixed costs.
+func (e *Estimator) txOverhead(ctx context.Context, params *channel.Params, state *channel.State, idx channel.Index) (uint64, error) {
+	msgs := make([]estimationMsg, 2)
+	for i := range msgs {
+		ch, err := newEstimationChannel(params, state, idx, int64(i))
+		if err != nil {
+			return 0, err
+		}
+		if msgs[i], err = ch.dispute(); err != nil {
+			return 0, err
+		}
+	}
+	gas, err := e.simulate(ctx, msgs)
+	if err != nil {
+		return 0, err
+	} else if gas[0] < gas[1] {
+		return 0, nil
+	}
+	return gas[0] - gas[1], nil
+}
+
+// simulateTxs simulates executing the given messages on the contract and
+// returns the gas used by each of them as a separate transaction, given the
+// fixed gas of a transaction.
+func (e *Estimator) simulateTxs(ctx context.Context, overhead uint64, msgs []estimationMsg) ([]uint64, error) {
+	gas, err := e.simulate(ctx, msgs)
+	if err != nil {
+		return nil, err
+	}
+	// The first message already includes the fixed costs.
+	for i := 1; i < len(gas); i++ {
+		gas[i] += overhead
+	}
+	return gas, nil
+}
+
+// simulate simulates executing the given messages on the contract.
+func (e *Estimator) simulate(ctx context.Context, msgs []estimationMsg) ([]uint64, error) {
+	_msgs := make([]*wtypes.MsgExecuteContract, len(msgs))
+	for i, msg := range msgs {
+		if err := e.contract.ValidateExecuteMsg(msg.msg); err != nil {
+			return nil, err
+		}
+		_msgs[i] = &wtypes.MsgExecuteContract{
+			Sender:   e.acc.String(),
+			Contract: e.contract.Address(),
+			Msg:      msg.msg,
+			Funds:    msg.funds,
+		}
+	}
+	return e.sim.SimulateExecuteContracts(ctx, _msgs)
+}
+
+// cost returns the cost of an operation that used the given gas in the
+// simulation. The fee is rounded up.
+func (e *Estimator) cost(simulated uint64) Cost {
+	gas := uint64(math.Ceil(float64(simulated) * e.gasAdjustment))
+	fee := e.gasPrice.Amount.MulInt64(int64(gas)).Ceil().TruncateInt()
+	return Cost{Gas: gas, Fee: types.NewCoin(e.gasPrice.Denom, fee)}
+}
+
+// estimationMsg is an execute message together with the attached funds.
+type estimationMsg struct {
+	msg   []byte
+	funds types.Coins
+}
+
+// estimationChannel is a channel with temporary participants that is used
+// for simulating the operations of one of them.
+type estimationChannel struct {
+	params *channel.Params
+	state  *channel.State
+	accs   []wallet.Account
+	idx    channel.Index
+}
+
+// newEstimationChannel creates a channel that resembles the given one but
+// has temporary participants, generated from the given seed, and in which
+// only the participant with the given index holds funds.
+func newEstimationChannel(params *channel.Params, state *channel.State, idx channel.Index, seed int64) (*estimationChannel, error) {
+	// The accounts are only used for signing simulated messages, so their
+	// keys need not be secret.
+	rng := rand.New(rand.NewSource(seed))
+	w := bwallet.NewWallet(keyring.NewInMemory())
+	accs := make([]wallet.Account, len(params.Parts))
+	parts := make([]wallet.Address, len(params.Parts))
+	for i := range accs {
+		acc, err := w.NewAccount(rng, fmt.Sprintf("estimation%d", i), "")
+		if err != nil {
+			return nil, err
+		}
+		accs[i], parts[i] = acc, acc.Address()
+	}
+
+	p := channel.NewParamsUnsafe(params.ChallengeDuration, parts, params.App, params.Nonce, params.LedgerChannel, params.VirtualChannel)
+	s := state.Clone()
+	s.ID = p.ID()
+	s.Version = 0
+	s.IsFinal = false
+	for _, bals := range s.Balances {
+		for i, bal := range bals {
+			if channel.Index(i) != idx {
+				bal.SetInt64(0)
+			}
+		}
+	}
+	return &estimationChannel{params: p, state: s, accs: accs, idx: idx}, nil
+}
+
+// deposits returns the deposit of the participant, or nothing if it has no
+// funds.
+func (c *estimationChannel) deposits(acc types.AccAddress) ([]estimationMsg, error) {
+	funds, err := EstimationFunds(c.state, c.idx)
+	if err != nil {
+		return nil, err
+	} else if funds.IsZero() {
+		return nil, nil
+	}
+	fID, err := binding.CalcFundingID(c.params.ID(), c.params.Parts[c.idx])
+	if err != nil {
+		return nil, err
+	}
+	msg, err := binding.NewDepositExecuteMsg(fID)
+	if err != nil {
+		return nil, err
+	}
+	return []estimationMsg{{msg, funds}}, nil
+}
+
+// dispute returns the registration of the initial state.
+func (c *estimationChannel) dispute() (estimationMsg, error) {
+	sigs, err := c.sign(c.state)
+	if err != nil {
+		return estimationMsg{}, err
+	}
+	msg, err := binding.NewDisputeExecuteMsg(*c.params, *c.state, sigs)
+	return estimationMsg{msg: msg}, err
+}
+
+// conclude returns the conclusion of a final state.
+func (c *estimationChannel) conclude() (estimationMsg, error) {
+	s := c.state.Clone()
+	s.Version = 1
+	s.IsFinal = true
+	sigs, err := c.sign(s)
+	if err != nil {
+		return estimationMsg{}, err
+	}
+	msg, err := binding.NewConcludeExecuteMsg(*c.params, *s, sigs)
+	return estimationMsg{msg: msg}, err
+}
+
+// withdrawal returns the withdrawal of the participant to the receiver.
+func (c *estimationChannel) withdrawal(receiver types.AccAddress) (estimationMsg, error) {
+	acc := c.accs[c.idx]
+	w := binding.NewWithdrawal(c.params.ID(), acc.Address(), receiver)
+	b, err := w.Bytes()
+	if err != nil {
+		return estimationMsg{}, err
+	}
+	sig, err := acc.SignData(b)
+	if err != nil {
+		return estimationMsg{}, err
+	}
+	msg, err := binding.NewWithdrawMsgExecute(c.params.ID(), acc.Address(), receiver, sig)
+	return estimationMsg{msg: msg}, err
+}
+
+func (c *estimationChannel) sign(s *channel.State) ([]wallet.Sig, error) {
+	sigs := make([]wallet.Sig, len(c.accs))
+	for i, acc := range c.accs {
+		var err error
+		if sigs[i], err = channel.Sign(acc, s); err != nil {
+			return nil, err
+		}
+	}
+	return sigs, nil
+}
@@ -0,0 +1,126 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/types"
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	ctest "perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+func TestEstimator(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	r := test.NewRandomGenerator(maxNumParts, maxNumAssets, big.NewInt(maxFundingAmount), int64(maxChallengeDuration.Seconds()))
+	gasPrice := types.NewDecCoinFromDec("stake", types.NewDecWithPrec(25, 3))
+	e := bchannel.NewEstimator(c, contract, c.Account(), gasPrice)
+
+	newChannel := func(numParts int, assets []channel.Asset) (*channel.Params, *channel.State) {
+		return r.NewParamsAndState(rng, ctest.WithNumParts(numParts), ctest.WithAssets(assets...), ctest.WithBalancesInRange(big.NewInt(1), big.NewInt(maxFundingAmount)))
+	}
+	estimate := func(e *bchannel.Estimator, params *channel.Params, state *channel.State, idx channel.Index) bchannel.Estimate {
+		coins, err := bchannel.EstimationFunds(state, idx)
+		require.NoError(t, err)
+		require.NoError(t, c.AddCoins(ctx, c.Account(), coins))
+
+		est, err := e.Estimate(ctx, params, state, idx)
+		require.NoError(t, err)
+		return est
+	}
+
+	assets := test.NewRandomAssets(rng, 2)
+	params, state := newChannel(2, assets)
+	small := estimate(e, params, state, 1)
+	for _, cost := range []bchannel.Cost{small.Deposit, small.Dispute, small.Conclude, small.Withdraw} {
+		assert.Positive(t, cost.Gas)
+		assert.Equal(t, gasPrice.Denom, cost.Fee.Denom)
+		assert.True(t, cost.Fee.IsPositive())
+	}
+	assert.Equal(t, small.Conclude.Gas+small.Withdraw.Gas, small.Close().Gas)
+
+	// Registering and concluding verify a signature per participant and
+	// transfer funds per participant and asset.
+	params, state = newChannel(4, test.NewRandomAssets(rng, 4))
+	large := estimate(e, params, state, 0)
+	assert.Greater(t, large.Dispute.Gas, small.Dispute.Gas)
+	assert.Greater(t, large.Conclude.Gas, small.Conclude.Gas)
+
+	t.Run("no funds", func(t *testing.T) {
+		params, state := newChannel(2, assets)
+		for _, bals := range state.Balances {
+			bals[0].SetInt64(0)
+		}
+		est := estimate(e, params, state, 0)
+		assert.Zero(t, est.Deposit.Gas, "no deposit")
+		assert.True(t, est.Deposit.Fee.IsZero(), "no deposit")
+		assert.Positive(t, est.Withdraw.Gas)
+	})
+
+	t.Run("transaction overhead", func(t *testing.T) {
+		// Every operation is a transaction of its own, so the fixed costs
+		// that a node includes in the first simulated message are added to
+		// each of them.
+		const overhead = 10000
+		noAdjustment := bchannel.EstimatorGasAdjustmentOpt(1)
+		params, state := newChannel(2, assets)
+		est := estimate(bchannel.NewEstimator(c, contract, c.Account(), gasPrice, noAdjustment), params, state, 0)
+		// The estimation does not change the state of the ledger.
+		withOverhead, err := bchannel.NewEstimator(overheadSimulator{c, overhead}, contract, c.Account(), gasPrice, noAdjustment).Estimate(ctx, params, state, 0)
+		require.NoError(t, err)
+		assert.Equal(t, est.Deposit.Gas+overhead, withOverhead.Deposit.Gas, "deposit")
+		assert.Equal(t, est.Dispute.Gas+overhead, withOverhead.Dispute.Gas, "dispute")
+		assert.Equal(t, est.Conclude.Gas+overhead, withOverhead.Conclude.Gas, "conclude")
+		assert.Equal(t, est.Withdraw.Gas+overhead, withOverhead.Withdraw.Gas, "withdraw")
+		assert.Equal(t, est.ForceClose().Gas+3*overhead, withOverhead.ForceClose().Gas, "force close")
+	})
+
+	t.Run("unfunded account", func(t *testing.T) {
+		// The deposit is simulated with the real amounts, so an account
+		// without the participant's funds cannot estimate its cost.
+		unfunded := bchannel.NewEstimator(c, contract, types.AccAddress(make([]byte, 20)), gasPrice)
+		params, state := newChannel(2, assets)
+		_, err := unfunded.Estimate(ctx, params, state, 0)
+		assert.Error(t, err)
+	})
+}
+
+// overheadSimulator is a simulator that includes fixed transaction costs in
+// the gas of the first message, like a node.
+type overheadSimulator struct {
+	client.Simulator
+	overhead uint64
+}
+
+func (s overheadSimulator) SimulateExecuteContracts(ctx context.Context, msgs []*wtypes.MsgExecuteContract) ([]uint64, error) {
+	gas, err := s.Simulator.SimulateExecuteContracts(ctx, msgs)
+	if err == nil && len(gas) > 0 {
+		gas[0] += s.overhead
+	}
+	return gas, err
+}
@@ -0,0 +1,85 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/fault"
+	ptest "github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+const faultLatency = 20 * time.Millisecond // The maximum latency injected into client calls.
+
+// newFaultScenario returns a seeded scenario of faults that are injected into
+// the client calls of the funder and adjudicator.
+func newFaultScenario(rng *rand.Rand) fault.Scenario {
+	return fault.Scenario{
+		Seed: rng.Int63(),
+		Faults: map[fault.Method]fault.Faults{
+			fault.ExecuteContract: {
+				Latency:       faultLatency,
+				Transient:     0.1,
+				Dropped:       0.2,
+				OutOfGas:      0.1,
+				Reordered:     0.2,
+				ReorderWindow: 2 * faultLatency,
+			},
+			fault.SmartContractState: {
+				Latency:       faultLatency,
+				Transient:     0.1,
+				Stale:         0.3,
+				Reordered:     0.2,
+				ReorderWindow: 2 * faultLatency,
+			},
+			fault.GetLatestBlock: {
+				Latency:   faultLatency,
+				Transient: 0.3,
+				Stale:     0.3,
+			},
+		},
+	}
+}
+
+// TestFunderWithFaults runs the funder tests with faults injected into the
+// client.
+func TestFunderWithFaults(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	fc := fault.NewClient(c, newFaultScenario(rng))
+	ptest.TestFunder(ctx, t, rng, newFunderSetup(ctx, rng, c, fc, contract))
+}
+
+// TestAdjudicatorWithFaults runs the adjudicator tests with faults injected
+// into the client.
+func TestAdjudicatorWithFaults(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	c.StartTicking(slowBlockTick, slowChainTick)
+	defer c.StopTicking()
+	fc := fault.NewClient(c, newFaultScenario(rng))
+	ptest.TestAdjudicatorWithSubscription(ctx, t, rng, newAdjudicatorSetup(ctx, c, fc, contract))
+}
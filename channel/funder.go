@@ -16,21 +16,28 @@ package channel
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/types"
 	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
 	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/proof"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/log"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/metrics"
 	perun "github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"perun.network/go-perun/channel"
 )
 
 // Funder provides methods for funding a channel.
 type Funder struct {
 	*contractClient
-	polling time.Duration
+	polling  time.Duration
+	strategy FundingStrategy
+	accounts []FundingAccount
 }
 
 type FunderOpt func(*Funder)
@@ -41,31 +48,217 @@ func FunderPollingIntervalOpt(d time.Duration) FunderOpt {
 	}
 }
 
-func NewFunder(c client.Client, contract client.ContractInstance, acc types.AccAddress, opts ...FunderOpt) *Funder {
+// FunderStrategyOpt sets the strategy that determines when the funder
+// deposits. By default, ConcurrentFundingStrategy is used.
+func FunderStrategyOpt(s FundingStrategy) FunderOpt {
+	return func(f *Funder) {
+		f.strategy = s
+	}
+}
+
+// FunderAccountsOpt makes the funder deposit the participant's share from
+// the given accounts instead of the funder's account. The share is split
+// across the accounts in order, each account depositing up to its limit, and
+// each account signs its own deposit. The deposits are made under the
+// participant's funding ID, so that the other participants observe a single
+// deposit. Every account other than the funder's account needs its own
+// client, otherwise NewFunder fails.
+func FunderAccountsOpt(accs ...FundingAccount) FunderOpt {
+	return func(f *Funder) {
+		f.accounts = append([]FundingAccount(nil), accs...)
+	}
+}
+
+// FunderRetryPolicyOpt sets the policy for retrying failed client calls.
+func FunderRetryPolicyOpt(p RetryPolicy) FunderOpt {
+	return func(f *Funder) {
+		f.retry = p
+	}
+}
+
+// FunderVerifiedQueriesOpt makes the funder read deposits with the given
+// querier, which verifies the contract state against trusted headers instead
+// of trusting the node.
+func FunderVerifiedQueriesOpt(q *proof.Querier) FunderOpt {
+	return func(f *Funder) {
+		f.verified = q
+	}
+}
+
+// FunderValidationOpt sets whether messages to and from the contract are
+// validated against its JSON schemas. Validation is enabled by default. It
+// catches encoding bugs early but costs time on every query, so it can be
+// disabled in production once the binding has been tested against the
+// deployed contract.
+func FunderValidationOpt(enabled bool) FunderOpt {
+	return func(f *Funder) {
+		f.validate = enabled
+	}
+}
+
+// FunderLoggerOpt sets the logger. By default, go-perun's framework logger
+// is used.
+func FunderLoggerOpt(l log.Logger) FunderOpt {
+	return func(f *Funder) {
+		f.log = l
+	}
+}
+
+// FunderMetricsOpt makes the funder record metrics. By default, no metrics
+// are recorded.
+func FunderMetricsOpt(m *metrics.Metrics) FunderOpt {
+	return func(f *Funder) {
+		f.metrics = m
+	}
+}
+
+// FunderTracerProviderOpt sets the provider of the tracer. By default,
+// OpenTelemetry's global tracer provider is used.
+func FunderTracerProviderOpt(tp trace.TracerProvider) FunderOpt {
+	return func(f *Funder) {
+		f.tracer = tp.Tracer(TracerName)
+	}
+}
+
+// NewFunder creates a funder for the given contract. It returns an error
+// wrapping ErrIncompatibleContract if the contract is not supported.
+func NewFunder(ctx context.Context, c client.Client, contract client.ContractInstance, acc types.AccAddress, opts ...FunderOpt) (*Funder, error) {
+	cc, err := newContractClient(ctx, c, contract, acc)
+	if err != nil {
+		return nil, fmt.Errorf("creating contract client: %w", err)
+	}
 	f := &Funder{
-		contractClient: newContractClient(c, contract, acc),
+		contractClient: cc,
 		polling:        defaultPollingInterval,
+		strategy:       ConcurrentFundingStrategy(),
 	}
 	for _, opt := range opts {
 		opt(f)
 	}
-	return f
+	if len(f.accounts) == 0 {
+		f.accounts = []FundingAccount{{Account: acc}}
+	}
+	for i, a := range f.accounts {
+		if a.Client != nil {
+			continue
+		}
+		// Node clients sign for a single account, so only the funder's
+		// account can use the funder's client.
+		if !a.Account.Equals(acc) {
+			return nil, fmt.Errorf("funding account %d: no client for account %v", i, a.Account)
+		}
+		f.accounts[i].Client = c
+	}
+	return f, nil
 }
 
 // Fund deposits funds according to the specified funding request and waits until the funding is complete.
+//
+// Fund only tops up the existing deposit of the participant to its share, so
+// it can be retried, e.g., after a crash. If the deposit exceeds the share,
+// the excess is logged and recorded as over-funded.
 func (f *Funder) Fund(ctx context.Context, req channel.FundingReq) error {
+	_, err := f.FundWithExcess(ctx, req)
+	return err
+}
+
+// FundWithExcess is like Fund but also returns the amount by which the
+// deposit of the participant exceeded its share before funding.
+func (f *Funder) FundWithExcess(ctx context.Context, req channel.FundingReq) (_ types.Coins, err error) {
+	start := time.Now()
+	defer func() { f.metrics.ObserveFunding(time.Since(start), err) }()
+	ctx, span := f.tracer.Start(ctx, SpanFund)
+	defer func() { endSpan(span, err) }()
+
 	_req := (*fundingReq)(&req)
 	fID, err := _req.ID()
 	if err != nil {
-		return fmt.Errorf("creating funding ID: %w", err)
+		return nil, fmt.Errorf("creating funding ID: %w", err)
+	}
+
+	id := req.Params.ID()
+	l := f.log.WithFields(log.Fields{
+		log.ChannelIDKey: hex.EncodeToString(id[:]),
+		log.PartIdxKey:   req.Idx,
+		log.FundingIDKey: hex.EncodeToString(fID),
+	})
+	span.SetAttributes(
+		channelIDAttribute(id),
+		attribute.Int(log.PartIdxKey, int(req.Idx)),
+		attribute.String(log.FundingIDKey, hex.EncodeToString(fID)),
+	)
+
+	funds, err := _req.Funds()
+	if err != nil {
+		return nil, fmt.Errorf("creating funds: %w", err)
+	}
+	shares, err := _req.shares(_req.parts())
+	if err != nil {
+		return nil, fmt.Errorf("creating funds: %w", err)
+	}
+	plan, err := f.strategy.plan(_req, funds)
+	if err != nil {
+		return nil, fmt.Errorf("planning deposits: %w", err)
+	}
+	excess, err := f.fundShare(ctx, l, _req, fID, funds, plan)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.awaitFundingComplete(ctx, l, _req, shares); err != nil {
+		return excess, err
+	}
+	l.Debugf("Funding complete")
+	return excess, nil
+}
+
+// fundShare deposits the share funds of the participant according to the
+// plan and returns the amount by which the deposit exceeded the share.
+// Deposits only top up the existing deposit, so that funding can be retried,
+// e.g., after a crash.
+func (f *Funder) fundShare(ctx context.Context, l log.Logger, req *fundingReq, fID binding.FundingID, funds types.Coins, plan fundingPlan) (types.Coins, error) {
+	excess := types.NewCoins()
+	if plan.await != nil {
+		firstExcess, err := f.depositShare(ctx, l, req, fID, plan.first)
+		if err != nil {
+			return nil, err
+		}
+		// The deposit exceeded the first part by firstExcess, but it only
+		// exceeded the share by what is left after the rest of the share.
+		_, excess = coinsDiff(funds, plan.first.Add(firstExcess...))
+		l.Debugf("Awaiting deposits %v", plan.await)
+		if err := f.awaitFundingComplete(ctx, l, req, plan.await); err != nil {
+			return nil, fmt.Errorf("awaiting deposits: %w", err)
+		}
+	}
+
+	restExcess, err := f.depositShare(ctx, l, req, fID, funds)
+	if err != nil {
+		return nil, err
+	}
+	// Both deposits report the excess of the deposit they saw over the
+	// share, so the larger one is kept per denomination.
+	_, more := coinsDiff(restExcess, excess)
+	excess = restExcess.Add(more...)
+	if !excess.IsZero() {
+		l.Warnf("Over-funded by %v", excess)
+		f.metrics.AddOverfunding(excess)
 	}
+	return excess, nil
+}
 
-	funds := _req.Funds()
-	err = f.deposit(ctx, fID, funds)
+// depositShare tops up the deposit to the given target and records the
+// deposited amount. It returns the amount by which the deposit exceeded the
+// target before topping up.
+func (f *Funder) depositShare(ctx context.Context, l log.Logger, req *fundingReq, fID binding.FundingID, target types.Coins) (types.Coins, error) {
+	deposited, excess, err := f.deposit(ctx, l, fID, target)
+	// Deposits of other accounts may have succeeded before one failed.
+	f.metrics.AddDeposit(deposited)
 	if err != nil {
-		return fmt.Errorf("depositing: %w", err)
+		err = fmt.Errorf("depositing: %w", err)
+		l.WithError(err).Errorf("Funding failed")
+		return nil, makePerunError(err, req.Params.ID(), txTypeDeposit)
 	}
-	return f.awaitFundingComplete(ctx, _req)
+	return excess, nil
 }
 
 type fundingReq channel.FundingReq
@@ -78,40 +271,130 @@ func (r *fundingReq) IDForPart(i channel.Index) (binding.FundingID, error) {
 	return binding.CalcFundingID(r.Params.ID(), r.Params.Parts[i])
 }
 
-func (r *fundingReq) Funds() types.Coins {
-	bals := perun.Balances(r.Agreement).ForPart(r.Idx)
+func (r *fundingReq) Funds() (types.Coins, error) {
+	return r.FundsForPart(r.Idx)
+}
+
+func (r *fundingReq) FundsForPart(i channel.Index) (types.Coins, error) {
+	bals := perun.Balances(r.Agreement).ForPart(i)
 	return binding.MakeCoins(r.State.Assets, bals)
 }
 
-func (r *fundingReq) TotalFunds() types.Coins {
-	return binding.MakeCoins(r.State.Assets, r.State.Allocation.Sum())
+// shares returns the shares of the given participants.
+func (r *fundingReq) shares(parts []channel.Index) (map[channel.Index]types.Coins, error) {
+	shares := make(map[channel.Index]types.Coins, len(parts))
+	for _, i := range parts {
+		funds, err := r.FundsForPart(i)
+		if err != nil {
+			return nil, err
+		}
+		shares[i] = funds
+	}
+	return shares, nil
 }
 
-// deposit sends a funding transaction.
-func (f *Funder) deposit(ctx context.Context, fID binding.FundingID, funds types.Coins) error {
-	msg, err := binding.NewDepositExecuteMsg(fID)
-	if err != nil {
-		return err
+// parts returns the indices of all participants.
+func (r *fundingReq) parts() []channel.Index {
+	parts := make([]channel.Index, len(r.Params.Parts))
+	for i := range parts {
+		parts[i] = channel.Index(i)
 	}
+	return parts
+}
 
-	_, err = f.Execute(ctx, msg, funds)
-	return err
+// others returns the indices of all participants except the requesting one.
+func (r *fundingReq) others() []channel.Index {
+	others := make([]channel.Index, 0, len(r.Params.Parts)-1)
+	for _, i := range r.parts() {
+		if i != r.Idx {
+			others = append(others, i)
+		}
+	}
+	return others
 }
 
-// awaitFundingComplete blocks until the funding of the specified channel is complete.
-func (f *Funder) awaitFundingComplete(ctx context.Context, req *fundingReq) error {
-	total := req.TotalFunds()
-	for {
-		funded := types.NewCoins()
-		for i := range req.Params.Parts {
-			_funded, err := f.queryDeposit(ctx, req, channel.Index(i))
+// deposit tops up the deposit for the funding ID to the target amount. It
+// returns the amount that it deposited and the amount by which the existing
+// deposit exceeded the target. Nothing is deposited if the existing deposit
+// covers the target. The target is split across the funding accounts, which
+// deposit their missing parts one after another.
+func (f *Funder) deposit(ctx context.Context, l log.Logger, fID binding.FundingID, target types.Coins) (_, _ types.Coins, err error) {
+	ctx, span := f.tracer.Start(ctx, SpanDeposit)
+	defer func() { endSpan(span, err) }()
+
+	msg, err := f.enc.NewDepositExecuteMsg(fID)
+	if err != nil {
+		return nil, nil, err
+	}
+	parts, err := splitShare(f.accounts, target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deposited, err := f.queryDepositByID(ctx, fID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying deposit: %w", err)
+	}
+	_, excess := coinsDiff(target, deposited)
+	total := types.NewCoins()
+	for i, missing := range missingParts(parts, deposited) {
+		acc := f.accounts[i]
+		l := l.WithField(log.AccountKey, acc.Account.String())
+		if missing.IsZero() {
+			l.Debugf("Deposit of account covers %v", parts[i])
+			continue
+		}
+		l.Debugf("Depositing %v", missing)
+
+		// The deposit has been applied if the deposited amount has
+		// increased accordingly.
+		want := deposited.Add(missing...)
+		landed := func(ctx context.Context) (bool, error) {
+			_deposited, err := f.queryDepositByID(ctx, fID)
 			if err != nil {
-				log.Printf("Warning: Error querying deposit: %v\n", err)
+				return false, err
 			}
-			funded = funded.Add(_funded...)
+			return _deposited.IsAllGTE(want), nil
 		}
 
-		if funded.IsAllGTE(total) {
+		if _, err := f.executeFrom(ctx, l, acc.Client, acc.Account, msg, missing, landed); err != nil {
+			return total, nil, fmt.Errorf("depositing from account %v: %w", acc.Account, err)
+		}
+		deposited, total = want, total.Add(missing...)
+		l.Debugf("Deposited %v of %v", deposited, target)
+	}
+	return total, excess, nil
+}
+
+// coinsDiff returns the amounts by which have falls short of want and by
+// which it exceeds want, per denomination.
+func coinsDiff(want, have types.Coins) (missing, excess types.Coins) {
+	missing, excess = types.NewCoins(), types.NewCoins()
+	for _, c := range want {
+		if h := have.AmountOf(c.Denom); h.LT(c.Amount) {
+			missing = missing.Add(types.NewCoin(c.Denom, c.Amount.Sub(h)))
+		}
+	}
+	for _, c := range have {
+		if w := want.AmountOf(c.Denom); w.LT(c.Amount) {
+			excess = excess.Add(types.NewCoin(c.Denom, c.Amount.Sub(w)))
+		}
+	}
+	return missing, excess
+}
+
+// awaitFundingComplete blocks until the deposit of each of the given
+// participants covers the participant's share. The deposits are checked per
+// participant, so that an excess deposit of one participant does not cover
+// the missing deposit of another.
+func (f *Funder) awaitFundingComplete(ctx context.Context, l log.Logger, req *fundingReq, shares map[channel.Index]types.Coins) error {
+	for {
+		funded, err := f.poll(ctx, l, req, shares)
+		if err != nil {
+			return err
+		}
+
+		if funded {
 			return nil
 		}
 
@@ -124,21 +407,35 @@ func (f *Funder) awaitFundingComplete(ctx context.Context, req *fundingReq) erro
 
 }
 
+// poll queries the deposits of the given participants and returns whether
+// each covers the participant's share. Deposits that cannot be queried due to
+// errors that persisted after retrying are not counted.
+func (f *Funder) poll(ctx context.Context, l log.Logger, req *fundingReq, shares map[channel.Index]types.Coins) (_ bool, err error) {
+	f.metrics.Poll(metrics.ComponentFunder)
+	ctx, span := f.tracer.Start(ctx, SpanPoll)
+	defer func() { endSpan(span, err) }()
+
+	funded := true
+	for i, share := range shares {
+		deposit, err := f.queryDeposit(ctx, req, i)
+		if err != nil {
+			if f.retry.classify(err) == ErrorPermanent {
+				return false, fmt.Errorf("querying deposit: %w", err)
+			}
+			l.WithError(err).Warnf("Querying deposit failed")
+		}
+		if !deposit.IsAllGTE(share) {
+			funded = false
+		}
+	}
+	return funded, nil
+}
+
 // queryDeposit queries the current deposit state for the given channel participant.
 func (f *Funder) queryDeposit(ctx context.Context, req *fundingReq, part channel.Index) (binding.DepositQueryResponse, error) {
 	fID, err := req.IDForPart(part)
 	if err != nil {
 		return nil, err
 	}
-	msg, err := binding.NewDepositQueryMsg(fID)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := f.Query(ctx, msg)
-	if err != nil {
-		return nil, err
-	}
-
-	return binding.DecodeDepositQueryResponse(resp.Data)
+	return f.queryDepositByID(ctx, fID)
 }
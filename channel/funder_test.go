@@ -20,13 +20,19 @@ import (
 	"math/rand"
 	"testing"
 
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/types"
 	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
 	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
 	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
 	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
 	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/simulation"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/metrics"
 	pchannel "github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel"
 	ptest "github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"perun.network/go-perun/channel"
 	ctest "perun.network/go-perun/channel/test"
 	pkgtest "perun.network/go-perun/pkg/test"
@@ -40,22 +46,41 @@ func TestFunder(t *testing.T) {
 
 	rng := pkgtest.Prng(t)
 	c, contract := test.NewTestClientWithContract(ctx, t)
+	ptest.TestFunder(ctx, t, rng, newFunderSetup(ctx, rng, c, c, contract))
+}
+
+// TestFunderWithoutValidation tests the funder with schema validation
+// disabled.
+func TestFunderWithoutValidation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	ptest.TestFunder(ctx, t, rng, newFunderSetup(ctx, rng, c, c, contract, bchannel.FunderValidationOpt(false)))
+}
 
+// newFunderSetup creates a funder setup whose funders use client cc. The
+// options are applied to the funders.
+func newFunderSetup(ctx context.Context, rng *rand.Rand, c *simulation.Client, cc client.Client, contract client.ContractInstance, opts ...bchannel.FunderOpt) *funder {
+	opts = append([]bchannel.FunderOpt{bchannel.FunderPollingIntervalOpt(polling)}, opts...)
 	numParts := 2 + rng.Intn(maxNumParts-2)
 	funders := make([]channel.Funder, numParts)
 	for i := range funders {
-		opt := bchannel.FunderPollingIntervalOpt(polling)
-		funders[i] = bchannel.NewFunder(c, contract, c.Account(), opt)
+		f, err := bchannel.NewFunder(ctx, cc, contract, c.Account(), opts...)
+		if err != nil {
+			panic(err)
+		}
+		funders[i] = f
 	}
 
-	_f := &funder{
+	return &funder{
 		client:   c,
 		contract: contract,
 		w:        wtest.RandomWallet(),
 		r:        test.NewRandomGenerator(maxNumParts, maxNumAssets, big.NewInt(maxFundingAmount), int64(maxChallengeDuration.Seconds())),
 		funders:  funders,
 	}
-	ptest.TestFunder(ctx, t, rng, _f)
 }
 
 // funder represents a funder for testing.
@@ -91,11 +116,132 @@ func newFundingRequest(ctx context.Context, params *channel.Params, state *chann
 		Agreement: state.Balances,
 	}
 
-	coins := binding.MakeCoins(state.Assets, pchannel.Balances(state.Balances).ForPart(idx))
-	err := client.AddCoins(ctx, client.Account(), coins)
+	coins, err := binding.MakeCoins(state.Assets, pchannel.Balances(state.Balances).ForPart(idx))
+	if err != nil {
+		panic(err)
+	}
+	err = client.AddCoins(ctx, client.Account(), coins)
 	if err != nil {
 		panic(err)
 	}
 
 	return req
 }
+
+// TestFunderTopUp tests that the funder only tops up existing deposits and
+// queryDeposit returns the deposit for the funding ID held by the contract.
+func queryDeposit(ctx context.Context, t *testing.T, c *simulation.Client, contract client.ContractInstance, fID binding.FundingID) types.Coins {
+	msg, err := binding.NewDepositQueryMsg(fID)
+	require.NoError(t, err)
+	resp, err := c.SmartContractState(ctx, &wtypes.QuerySmartContractStateRequest{Address: contract.Address(), QueryData: msg})
+	require.NoError(t, err)
+	coins, err := binding.DecodeDepositQueryResponse(resp.Data)
+	require.NoError(t, err)
+	return coins
+}
+
+// reports over-funding.
+func TestFunderTopUp(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	rec := &depositRecorder{Client: c}
+	reg := prometheus.NewRegistry()
+	m, err := metrics.New(reg)
+	require.NoError(t, err)
+	f, err := bchannel.NewFunder(ctx, rec, contract, c.Account(), bchannel.FunderPollingIntervalOpt(polling), bchannel.FunderMetricsOpt(m))
+	require.NoError(t, err)
+	r := test.NewRandomGenerator(maxNumParts, maxNumAssets, big.NewInt(maxFundingAmount), int64(maxChallengeDuration.Seconds()))
+
+	// newRequest returns a funding request for a new channel with a single
+	// participant, its funding ID and its share.
+	newRequest := func() (channel.FundingReq, binding.FundingID, types.Coins) {
+		params, state := r.NewParamsAndState(rng, ctest.WithNumParts(1), ctest.WithBalancesInRange(big.NewInt(2), big.NewInt(maxFundingAmount)))
+		req := newFundingRequest(ctx, params, state, 0, c)
+		fID, err := binding.CalcFundingID(params.ID(), params.Parts[0])
+		require.NoError(t, err)
+		share, err := binding.MakeCoins(state.Assets, pchannel.Balances(state.Balances).ForPart(0))
+		require.NoError(t, err)
+		return *req, fID, share
+	}
+	// depositDirectly deposits the coins without the funder.
+	depositDirectly := func(fID binding.FundingID, coins types.Coins) {
+		require.NoError(t, c.AddCoins(ctx, c.Account(), coins))
+		msg, err := binding.NewDepositExecuteMsg(fID)
+		require.NoError(t, err)
+		_, err = c.ExecuteContract(ctx, &wtypes.MsgExecuteContract{
+			Sender:   c.Account().String(),
+			Contract: contract.Address(),
+			Msg:      msg,
+			Funds:    coins,
+		})
+		require.NoError(t, err)
+	}
+	// half returns the coins halved.
+	half := func(coins types.Coins) types.Coins {
+		h := types.NewCoins()
+		for _, c := range coins {
+			h = h.Add(types.NewCoin(c.Denom, c.Amount.QuoRaw(2)))
+		}
+		return h
+	}
+
+	t.Run("repeat", func(t *testing.T) {
+		req, fID, share := newRequest()
+		require.NoError(t, f.Fund(ctx, req))
+		require.NoError(t, f.Fund(ctx, req))
+		deposits := rec.recorded()
+		require.Len(t, deposits, 1)
+		assert.True(t, share.IsEqual(deposits[0].funds))
+		assert.True(t, share.IsEqual(queryDeposit(ctx, t, c, contract, fID)))
+	})
+
+	t.Run("top up", func(t *testing.T) {
+		req, fID, share := newRequest()
+		depositDirectly(fID, half(share))
+		require.NoError(t, f.Fund(ctx, req))
+		deposits := rec.recorded()
+		require.Len(t, deposits, 1)
+		assert.True(t, share.Sub(half(share)).IsEqual(deposits[0].funds))
+		assert.True(t, share.IsEqual(queryDeposit(ctx, t, c, contract, fID)))
+	})
+
+	t.Run("over-funded", func(t *testing.T) {
+		req, fID, share := newRequest()
+		excess := half(share)
+		depositDirectly(fID, share.Add(excess...))
+		total := types.ZeroInt()
+		for _, c := range excess {
+			total = total.Add(c.Amount)
+		}
+		before := gather(t, reg)["perun_cosmwasm_overfunded_total"]
+
+		// Retrying reports the same excess and records it again.
+		for i := 1; i <= 2; i++ {
+			reported, err := f.FundWithExcess(ctx, req)
+			require.NoError(t, err)
+			assert.True(t, excess.IsEqual(reported), "returned excess")
+			assert.Empty(t, rec.recorded())
+			assert.EqualValues(t, int64(i)*total.Int64(), gather(t, reg)["perun_cosmwasm_overfunded_total"]-before)
+		}
+	})
+
+	t.Run("over-funded capped", func(t *testing.T) {
+		// The first deposit of the capped strategy sees the excess over its
+		// cap, of which only the excess over the share is reported.
+		capped, err := bchannel.NewFunder(ctx, rec, contract, c.Account(),
+			bchannel.FunderPollingIntervalOpt(polling),
+			bchannel.FunderStrategyOpt(bchannel.CappedFundingStrategy(types.NewCoins())))
+		require.NoError(t, err)
+		req, fID, share := newRequest()
+		excess := half(share)
+		depositDirectly(fID, share.Add(excess...))
+
+		reported, err := capped.FundWithExcess(ctx, req)
+		require.NoError(t, err)
+		assert.True(t, excess.IsEqual(reported), "returned excess")
+		assert.Empty(t, rec.recorded())
+	})
+}
@@ -35,6 +35,8 @@ const maxChallengeDuration = 3600 * time.Second // The maximum channel challenge
 const testTimeout = 30 * time.Second            // The duration after a test times out.
 const blockTick = 10 * time.Millisecond         // The interval at which the simulated blockchain ticks.
 const simChainTick = 60 * time.Second           // The amount of time that is added each blockchain tick.
+const slowBlockTick = 50 * time.Millisecond     // The block tick for tests in which reads or retries are delayed.
+const slowChainTick = 20 * time.Second          // The time added each slow block tick.
 
 // init sets the global variables for testing.
 func init() {
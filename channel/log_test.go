@@ -0,0 +1,69 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/log"
+	"github.com/sirupsen/logrus"
+	ltest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ctest "perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+func TestFunderLogging(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	r := test.NewRandomGenerator(maxNumParts, maxNumAssets, big.NewInt(maxFundingAmount), int64(maxChallengeDuration.Seconds()))
+	params, state := r.NewParamsAndState(rng, ctest.WithNumParts(1))
+	req := newFundingRequest(ctx, params, state, 0, c)
+
+	logger, hook := ltest.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	f, err := bchannel.NewFunder(ctx, c, contract, c.Account(),
+		bchannel.FunderPollingIntervalOpt(polling),
+		bchannel.FunderLoggerOpt(log.FromLogrus(logger)))
+	require.NoError(t, err)
+	require.NoError(t, f.Fund(ctx, *req))
+
+	fID, err := binding.CalcFundingID(params.ID(), params.Parts[0])
+	require.NoError(t, err)
+	id := params.ID()
+
+	var executed *logrus.Entry
+	for _, e := range hook.AllEntries() {
+		assert.Equal(t, hex.EncodeToString(id[:]), e.Data[log.ChannelIDKey])
+		assert.Equal(t, hex.EncodeToString(fID), e.Data[log.FundingIDKey])
+		assert.EqualValues(t, 0, e.Data[log.PartIdxKey])
+		if e.Message == "Executed contract" {
+			executed = e
+		}
+	}
+	require.NotNil(t, executed, "deposit logged")
+	assert.NotEmpty(t, executed.Data[log.TxHashKey])
+	assert.NotEmpty(t, executed.Data[log.HeightKey])
+}
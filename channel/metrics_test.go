@@ -0,0 +1,91 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/fault"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ctest "perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+func TestFunderMetrics(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	r := test.NewRandomGenerator(maxNumParts, maxNumAssets, big.NewInt(maxFundingAmount), int64(maxChallengeDuration.Seconds()))
+	params, state := r.NewParamsAndState(rng, ctest.WithNumParts(1))
+	req := newFundingRequest(ctx, params, state, 0, c)
+
+	reg := prometheus.NewRegistry()
+	m, err := metrics.New(reg)
+	require.NoError(t, err)
+	fc := fault.NewClient(c, fault.Scenario{Faults: map[fault.Method]fault.Faults{
+		fault.SmartContractState: {Transient: 0.3},
+	}})
+	f, err := bchannel.NewFunder(ctx, metrics.NewClient(fc, m), contract, c.Account(),
+		bchannel.FunderPollingIntervalOpt(polling),
+		bchannel.FunderMetricsOpt(m))
+	require.NoError(t, err)
+	require.NoError(t, f.Fund(ctx, *req))
+
+	values := gather(t, reg)
+	assert.EqualValues(t, 1, values["perun_cosmwasm_funding_duration_seconds"])
+	assert.Zero(t, values["perun_cosmwasm_funding_failures_total"])
+	funds := state.Allocation.Sum()
+	total := new(big.Int)
+	for _, b := range funds {
+		total.Add(total, b)
+	}
+	deposited, _ := big.NewFloat(values["perun_cosmwasm_deposited_total"]).Int(nil)
+	assert.Equal(t, total, deposited)
+	assert.EqualValues(t, 1, values["perun_cosmwasm_gas_used"])
+	assert.Positive(t, values["perun_cosmwasm_polls_total"])
+	assert.GreaterOrEqual(t, values["perun_cosmwasm_rpc_calls_total"], values["perun_cosmwasm_polls_total"]+1)
+}
+
+// gather returns the values of the metrics in the registry, summed over all
+// labels. For histograms, the sample count is returned.
+func gather(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				values[mf.GetName()] += m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				values[mf.GetName()] += m.GetGauge().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				values[mf.GetName()] += float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,146 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"syscall"
+	"time"
+
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/proof"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorClass classifies errors returned by client calls.
+type ErrorClass int
+
+const (
+	// ErrorPermanent indicates that the call must not be retried.
+	ErrorPermanent ErrorClass = iota
+	// ErrorTransient indicates that the call did not reach the ledger and can
+	// be retried safely.
+	ErrorTransient
+	// ErrorUncertain indicates that the call may or may not have been
+	// processed by the ledger. Queries can be retried safely, transactions
+	// only if it is known that they have not been applied.
+	ErrorUncertain
+)
+
+// RetryPolicy describes how failed client calls are retried.
+//
+// The backoff before attempt n+1 is InitialBackoff * Multiplier^(n-1), capped
+// at MaxBackoff, and randomized by up to the fraction Jitter in either
+// direction.
+type RetryPolicy struct {
+	MaxAttempts    int // The maximum number of attempts, including the first one.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64
+	// Classify classifies errors. If nil, ClassifyError is used.
+	Classify func(error) ErrorClass
+}
+
+// DefaultRetryPolicy returns the retry policy that is used by default.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// NoRetryPolicy returns a retry policy that disables retries.
+func NoRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// ClassifyError is the default error classification. Contract errors and
+// context errors are permanent. Connection failures and gRPC errors
+// indicating that the request was not processed are transient, as are
+// transactions that ran out of gas, because they are reverted and the gas is
+// estimated anew on every attempt, and verified queries for state that is not
+// committed yet. gRPC deadline errors are uncertain. All other errors are
+// permanent.
+func ClassifyError(err error) ErrorClass {
+	var cErr *binding.ContractError
+	switch {
+	case err == nil,
+		errors.As(err, &cErr),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return ErrorPermanent
+	case errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, sdkerrors.ErrOutOfGas),
+		errors.Is(err, proof.ErrNotCommitted):
+		return ErrorTransient
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return ErrorPermanent
+	}
+	switch grpcErr.GRPCStatus().Code() {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return ErrorTransient
+	case codes.DeadlineExceeded:
+		return ErrorUncertain
+	}
+	return ErrorPermanent
+}
+
+func (p RetryPolicy) classify(err error) ErrorClass {
+	if p.Classify != nil {
+		return p.Classify(err)
+	}
+	return ClassifyError(err)
+}
+
+// do calls fn until it succeeds, fn reports that the call must not be
+// retried, the maximum number of attempts is reached, or the context is done.
+// It returns the error of the last attempt.
+func (p RetryPolicy) do(ctx context.Context, fn func() (retry bool, err error)) error {
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := fn()
+		if err == nil || !retry || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(p.jitter(backoff)):
+		}
+
+		backoff = time.Duration(float64(backoff) * p.Multiplier)
+		if backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// jitter randomizes the given backoff.
+func (p RetryPolicy) jitter(d time.Duration) time.Duration {
+	f := 1 + p.Jitter*(2*rand.Float64()-1)
+	return time.Duration(float64(d) * f)
+}
@@ -0,0 +1,112 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/fault"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/proof"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"perun.network/go-perun/channel"
+	ctest "perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err   error
+		class bchannel.ErrorClass
+	}{
+		{nil, bchannel.ErrorPermanent},
+		{errors.New("some error"), bchannel.ErrorPermanent},
+		{context.DeadlineExceeded, bchannel.ErrorPermanent},
+		{binding.ParseContractError(errors.New("Unauthorized")), bchannel.ErrorPermanent},
+		{fmt.Errorf("dialing: %w", syscall.ECONNREFUSED), bchannel.ErrorTransient},
+		{status.Error(codes.Unavailable, ""), bchannel.ErrorTransient},
+		{status.Error(codes.ResourceExhausted, ""), bchannel.ErrorTransient},
+		{sdkerrors.Wrap(sdkerrors.ErrOutOfGas, "out of gas in location: wasm"), bchannel.ErrorTransient},
+		{sdkerrors.ABCIError(sdkerrors.RootCodespace, sdkerrors.ErrOutOfGas.ABCICode(), "out of gas"), bchannel.ErrorTransient},
+		{fmt.Errorf("querying proof: %w", proof.ErrNotCommitted), bchannel.ErrorTransient},
+		{status.Error(codes.DeadlineExceeded, ""), bchannel.ErrorUncertain},
+		{status.Error(codes.InvalidArgument, ""), bchannel.ErrorPermanent},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.class, bchannel.ClassifyError(tt.err), "%v", tt.err)
+	}
+}
+
+// executeCounter is a client that counts the transactions that reach the
+// ledger.
+type executeCounter struct {
+	client.Client
+	count int32
+}
+
+func (c *executeCounter) ExecuteContract(ctx context.Context, in *wtypes.MsgExecuteContract, opts ...grpc.CallOption) (*wtypes.MsgExecuteContractResponse, error) {
+	atomic.AddInt32(&c.count, 1)
+	return c.Client.ExecuteContract(ctx, in, opts...)
+}
+
+func TestFunderRetry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	r := test.NewRandomGenerator(maxNumParts, maxNumAssets, big.NewInt(maxFundingAmount), int64(maxChallengeDuration.Seconds()))
+	newRequest := func() channel.FundingReq {
+		params, state := r.NewParamsAndState(rng, ctest.WithNumParts(1))
+		return *newFundingRequest(ctx, params, state, 0, c)
+	}
+
+	t.Run("dropped", func(t *testing.T) {
+		cc := &executeCounter{Client: c}
+		fc := fault.NewClient(cc, fault.Scenario{Faults: map[fault.Method]fault.Faults{
+			fault.ExecuteContract: {Dropped: 1},
+		}})
+		f, err := bchannel.NewFunder(ctx, fc, contract, c.Account(), bchannel.FunderPollingIntervalOpt(polling))
+		require.NoError(t, err)
+		require.NoError(t, f.Fund(ctx, newRequest()))
+		assert.EqualValues(t, 1, cc.count, "deposited once")
+	})
+
+	t.Run("no retry", func(t *testing.T) {
+		fc := fault.NewClient(c, fault.Scenario{Faults: map[fault.Method]fault.Faults{
+			fault.SmartContractState: {Transient: 1},
+		}})
+		f, err := bchannel.NewFunder(ctx, fc, contract, c.Account(),
+			bchannel.FunderPollingIntervalOpt(polling),
+			bchannel.FunderRetryPolicyOpt(bchannel.NoRetryPolicy()))
+		require.NoError(t, err)
+		assert.ErrorIs(t, f.Fund(ctx, newRequest()), fault.ErrTransient)
+	})
+}
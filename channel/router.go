@@ -0,0 +1,435 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"perun.network/go-perun/channel"
+)
+
+var (
+	// ErrNoRoute is returned if a channel cannot be routed to a chain.
+	ErrNoRoute = errors.New("no route")
+	// ErrAmbiguousRoute is returned if a channel that is not registered
+	// with the router can be routed to more than one chain.
+	ErrAmbiguousRoute = errors.New("ambiguous route")
+)
+
+// Chain is a ledger on which channels can be opened. Funder and Adjudicator
+// are usually created for the Perun contract on the chain.
+type Chain struct {
+	ID          string   // Identifies the chain, e.g., by its chain ID.
+	Denoms      []string // Denominations of the assets held on the chain.
+	Funder      channel.Funder
+	Adjudicator channel.Adjudicator
+}
+
+// Router routes channels to chains.
+//
+// A channel is routed to the chain for which it has been registered with
+// SetChannelChain. Otherwise, it is routed to the only chain that holds all of
+// its assets and registered for that chain, so that later requests which do
+// not carry the assets, like subscriptions, take the same route.
+type Router struct {
+	mu       sync.RWMutex
+	chains   map[string]*Chain
+	channels map[channel.ID]string
+}
+
+// NewRouter creates a router for the given chains. Chain IDs must be unique
+// and not empty.
+func NewRouter(chains ...Chain) (*Router, error) {
+	r := &Router{
+		chains:   make(map[string]*Chain, len(chains)),
+		channels: make(map[channel.ID]string),
+	}
+	for i := range chains {
+		c := chains[i]
+		if c.ID == "" {
+			return nil, fmt.Errorf("chain %d: empty ID", i)
+		}
+		if _, ok := r.chains[c.ID]; ok {
+			return nil, fmt.Errorf("chain %d: duplicate ID %q", i, c.ID)
+		}
+		r.chains[c.ID] = &c
+	}
+	return r, nil
+}
+
+// SetChannelChain registers the channel for the given chain. Registered
+// channels are routed to their chain regardless of their assets.
+func (r *Router) SetChannelChain(id channel.ID, chain string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.chains[chain]; !ok {
+		return fmt.Errorf("%w: unknown chain %q", ErrNoRoute, chain)
+	}
+	r.channels[id] = chain
+	return nil
+}
+
+// ChannelChain returns the chain for which the channel is registered.
+func (r *Router) ChannelChain(id channel.ID) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	chain, ok := r.channels[id]
+	return chain, ok
+}
+
+// RemoveChannel removes the registration of the channel. MultiAdjudicator
+// removes it once the channel has been withdrawn.
+func (r *Router) RemoveChannel(id channel.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.channels, id)
+}
+
+// route returns the chain of the channel. If the channel is not registered,
+// it is routed by its assets and registered. If assets is nil, only
+// registered channels are routed.
+func (r *Router) route(id channel.ID, assets []channel.Asset) (*Chain, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if chain, ok := r.channels[id]; ok {
+		return r.chains[chain], nil
+	}
+	if assets == nil {
+		return nil, fmt.Errorf("%w: unknown channel %x", ErrNoRoute, id)
+	}
+
+	denoms := make([]string, len(assets))
+	for i, a := range assets {
+		denom, ok := a.(binding.Asset)
+		if !ok {
+			return nil, fmt.Errorf("%w: asset %d has type %T", ErrNoRoute, i, a)
+		}
+		denoms[i] = string(denom)
+	}
+
+	var candidates []string
+	for name, c := range r.chains {
+		if holdsAll(c.Denoms, denoms) {
+			candidates = append(candidates, name)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("%w: no chain holds assets %v", ErrNoRoute, denoms)
+	case 1:
+		r.channels[id] = candidates[0]
+		return r.chains[candidates[0]], nil
+	}
+	sort.Strings(candidates)
+	return nil, fmt.Errorf("%w: assets %v are held on chains %s", ErrAmbiguousRoute, denoms, strings.Join(candidates, ", "))
+}
+
+func holdsAll(held, denoms []string) bool {
+	for _, d := range denoms {
+		found := false
+		for _, h := range held {
+			if h == d {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Router) funder(id channel.ID, assets []channel.Asset) (channel.Funder, error) {
+	c, err := r.route(id, assets)
+	if err != nil {
+		return nil, err
+	}
+	if c.Funder == nil {
+		return nil, fmt.Errorf("%w: chain %q has no funder", ErrNoRoute, c.ID)
+	}
+	return c.Funder, nil
+}
+
+func (r *Router) adjudicator(id channel.ID, assets []channel.Asset) (channel.Adjudicator, error) {
+	c, err := r.route(id, assets)
+	if err != nil {
+		return nil, err
+	}
+	if c.Adjudicator == nil {
+		return nil, fmt.Errorf("%w: chain %q has no adjudicator", ErrNoRoute, c.ID)
+	}
+	return c.Adjudicator, nil
+}
+
+// MultiFunder is a funder that forwards each funding request to the funder
+// of the chain of the channel.
+type MultiFunder struct {
+	router *Router
+}
+
+// NewMultiFunder creates a funder that routes requests with the given router.
+func NewMultiFunder(r *Router) *MultiFunder {
+	return &MultiFunder{router: r}
+}
+
+// Fund funds the channel on its chain.
+func (f *MultiFunder) Fund(ctx context.Context, req channel.FundingReq) error {
+	funder, err := f.router.funder(req.Params.ID(), req.State.Assets)
+	if err != nil {
+		return fmt.Errorf("routing: %w", err)
+	}
+	return funder.Fund(ctx, req)
+}
+
+// MultiAdjudicator is an adjudicator that forwards each request to the
+// adjudicator of the chain of the channel.
+type MultiAdjudicator struct {
+	router *Router
+}
+
+// NewMultiAdjudicator creates an adjudicator that routes requests with the
+// given router.
+func NewMultiAdjudicator(r *Router) *MultiAdjudicator {
+	return &MultiAdjudicator{router: r}
+}
+
+// Register registers the channel on its chain.
+func (a *MultiAdjudicator) Register(ctx context.Context, req channel.AdjudicatorReq, subChannels []channel.SignedState) error {
+	adj, err := a.router.adjudicator(req.Params.ID(), req.Tx.State.Assets)
+	if err != nil {
+		return fmt.Errorf("routing: %w", err)
+	}
+	return adj.Register(ctx, req, subChannels)
+}
+
+// Withdraw withdraws the funds of the channel on its chain. Afterwards, the
+// channel is removed from the router.
+func (a *MultiAdjudicator) Withdraw(ctx context.Context, req channel.AdjudicatorReq, subStates channel.StateMap) error {
+	adj, err := a.router.adjudicator(req.Params.ID(), req.Tx.State.Assets)
+	if err != nil {
+		return fmt.Errorf("routing: %w", err)
+	}
+	if err := adj.Withdraw(ctx, req, subStates); err != nil {
+		return err
+	}
+	a.router.RemoveChannel(req.Params.ID())
+	return nil
+}
+
+// Progress progresses the channel on its chain.
+func (a *MultiAdjudicator) Progress(ctx context.Context, req channel.ProgressReq) error {
+	adj, err := a.router.adjudicator(req.Params.ID(), req.Tx.State.Assets)
+	if err != nil {
+		return fmt.Errorf("routing: %w", err)
+	}
+	return adj.Progress(ctx, req)
+}
+
+// Subscribe subscribes to the events of the channel on its chain. As the
+// request does not contain the assets of the channel, a channel that has not
+// been routed before, e.g., after a restart, is subscribed to on all chains.
+// The channel is then routed to the chain of its first event.
+func (a *MultiAdjudicator) Subscribe(ctx context.Context, id channel.ID) (channel.AdjudicatorSubscription, error) {
+	adj, err := a.router.adjudicator(id, nil)
+	if err == nil {
+		return adj.Subscribe(ctx, id)
+	} else if !errors.Is(err, ErrNoRoute) {
+		return nil, fmt.Errorf("routing: %w", err)
+	}
+	return a.subscribeAll(ctx, id)
+}
+
+// subscribeAll subscribes to the events of the channel on all chains that
+// have an adjudicator.
+func (a *MultiAdjudicator) subscribeAll(ctx context.Context, id channel.ID) (channel.AdjudicatorSubscription, error) {
+	a.router.mu.RLock()
+	var chains []*Chain
+	for _, c := range a.router.chains {
+		if c.Adjudicator != nil {
+			chains = append(chains, c)
+		}
+	}
+	a.router.mu.RUnlock()
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("routing: %w: no chain has an adjudicator", ErrNoRoute)
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i].ID < chains[j].ID })
+
+	s := &routedSubscription{
+		router: a.router,
+		id:     id,
+		chain:  -1,
+		events: make(chan routedEvent),
+		done:   make(chan struct{}),
+	}
+	for _, c := range chains {
+		sub, err := c.Adjudicator.Subscribe(ctx, id)
+		if err != nil {
+			_ = s.Close()
+			return nil, fmt.Errorf("subscribing on chain %q: %w", c.ID, err)
+		}
+		s.chains = append(s.chains, c.ID)
+		s.subs = append(s.subs, sub)
+	}
+	for i := range s.subs {
+		go s.forward(i)
+	}
+	return s, nil
+}
+
+// routedSubscription is a subscription to the events of a channel on several
+// chains. Once an event is received from one of the chains, the channel is
+// routed to that chain and the subscriptions on the other chains are closed.
+type routedSubscription struct {
+	router *Router
+	id     channel.ID
+	chains []string
+	subs   []channel.AdjudicatorSubscription
+	events chan routedEvent
+	done   chan struct{}
+	chain  int // Index of the chain of the channel, -1 if not known yet.
+	ended  int // Number of subscriptions that have ended.
+	failed int // Number of subscriptions that failed before routing.
+
+	closeOnce sync.Once
+	closeErr  error
+	mu        sync.Mutex
+	err       error
+}
+
+// routedEvent is an event of the subscription with the given index. A nil
+// event signals the end of the subscription.
+type routedEvent struct {
+	sub int
+	e   channel.AdjudicatorEvent
+}
+
+// forward forwards the events of subscription i until it ends or the routed
+// subscription is closed.
+func (s *routedSubscription) forward(i int) {
+	for {
+		e := s.subs[i].Next()
+		select {
+		case s.events <- routedEvent{sub: i, e: e}:
+		case <-s.done:
+			return
+		}
+		if e == nil {
+			return
+		}
+	}
+}
+
+// Next returns the next event of the channel on its chain. It returns nil if
+// the subscription is closed, the subscription on the chain of the channel
+// failed or, before the channel is routed, all of the subscriptions failed.
+// Subscriptions failing before the channel is routed are ignored otherwise.
+func (s *routedSubscription) Next() channel.AdjudicatorEvent {
+	for {
+		var re routedEvent
+		select {
+		case re = <-s.events:
+		case <-s.done:
+			return nil
+		}
+		if s.chain >= 0 && re.sub != s.chain {
+			continue
+		}
+
+		if re.e == nil {
+			err := s.subs[re.sub].Err()
+			if err != nil {
+				err = fmt.Errorf("chain %q: %w", s.chains[re.sub], err)
+			}
+			if s.chain >= 0 {
+				if err != nil {
+					s.fail(err)
+				}
+				return nil
+			}
+
+			// Before the channel is routed, failed subscriptions are closed
+			// and ignored as long as another chain may still route it.
+			if err != nil {
+				_ = s.subs[re.sub].Close()
+				s.failed++
+			}
+			s.ended++
+			if s.ended == len(s.subs) {
+				if s.failed == len(s.subs) {
+					s.fail(err)
+				}
+				return nil
+			}
+			continue
+		}
+
+		if s.chain < 0 {
+			s.route(re.sub)
+		}
+		return re.e
+	}
+}
+
+// route routes the channel to the chain of subscription i and closes the
+// subscriptions on the other chains.
+func (s *routedSubscription) route(i int) {
+	s.chain = i
+	if err := s.router.SetChannelChain(s.id, s.chains[i]); err != nil {
+		s.fail(err)
+	}
+	for j, sub := range s.subs {
+		if j != i {
+			_ = sub.Close()
+		}
+	}
+}
+
+// fail records the error and closes the subscription.
+func (s *routedSubscription) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	_ = s.Close()
+}
+
+// Err returns the error of the subscription.
+func (s *routedSubscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close closes the subscriptions on all chains.
+func (s *routedSubscription) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		for i, sub := range s.subs {
+			if err := sub.Close(); err != nil && s.closeErr == nil {
+				s.closeErr = fmt.Errorf("chain %q: %w", s.chains[i], err)
+			}
+		}
+	})
+	return s.closeErr
+}
@@ -0,0 +1,312 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"math/rand"
+	"sync"
+	"testing"
+
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	ptest "github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	ctest "perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+// recorder is a funder and adjudicator that records the channels of the
+// requests it receives.
+type recorder struct {
+	channel.Adjudicator
+	calls []channel.ID
+	subs  []*testSubscription
+}
+
+func (r *recorder) Fund(_ context.Context, req channel.FundingReq) error {
+	r.calls = append(r.calls, req.Params.ID())
+	return nil
+}
+
+func (r *recorder) Register(_ context.Context, req channel.AdjudicatorReq, _ []channel.SignedState) error {
+	r.calls = append(r.calls, req.Params.ID())
+	return nil
+}
+
+func (r *recorder) Withdraw(_ context.Context, req channel.AdjudicatorReq, _ channel.StateMap) error {
+	r.calls = append(r.calls, req.Params.ID())
+	return nil
+}
+
+func (r *recorder) Subscribe(_ context.Context, id channel.ID) (channel.AdjudicatorSubscription, error) {
+	r.calls = append(r.calls, id)
+	sub := &testSubscription{events: make(chan channel.AdjudicatorEvent, 1), closed: make(chan struct{})}
+	r.subs = append(r.subs, sub)
+	return sub, nil
+}
+
+// testSubscription is a subscription that returns the events sent on its
+// events channel.
+type testSubscription struct {
+	events chan channel.AdjudicatorEvent
+	closed chan struct{}
+	once   sync.Once
+	err    error // Set before closing by fail.
+}
+
+func (s *testSubscription) Next() channel.AdjudicatorEvent {
+	select {
+	case e := <-s.events:
+		return e
+	case <-s.closed:
+		return nil
+	}
+}
+
+func (s *testSubscription) Err() error {
+	return s.err
+}
+
+func (s *testSubscription) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+// fail ends the subscription with the given error.
+func (s *testSubscription) fail(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.closed)
+	})
+}
+
+func (s *testSubscription) isClosed() bool {
+	select {
+	case <-s.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+	rng := pkgtest.Prng(t)
+	gen := test.NewRandomGenerator(maxNumParts, maxNumAssets, big.NewInt(maxFundingAmount), int64(maxChallengeDuration.Seconds()))
+	newChannel := func(denoms ...string) (*channel.Params, *channel.State) {
+		assets := make([]channel.Asset, len(denoms))
+		for i, d := range denoms {
+			assets[i] = binding.Asset(d)
+		}
+		return gen.NewParamsAndState(rng, ctest.WithAssets(assets...), ctest.WithoutApp())
+	}
+
+	a, b := new(recorder), new(recorder)
+	r, err := bchannel.NewRouter(
+		bchannel.Chain{ID: "a", Denoms: []string{"atom", "stake"}, Funder: a, Adjudicator: a},
+		bchannel.Chain{ID: "b", Denoms: []string{"osmo", "stake"}, Funder: b, Adjudicator: b},
+	)
+	require.NoError(t, err)
+	f, adj := bchannel.NewMultiFunder(r), bchannel.NewMultiAdjudicator(r)
+
+	t.Run("by assets", func(t *testing.T) {
+		params, state := newChannel("atom", "stake")
+		require.NoError(t, f.Fund(ctx, channel.FundingReq{Params: params, State: state}))
+		assert.Equal(t, []channel.ID{params.ID()}, a.calls[len(a.calls)-1:])
+
+		chain, ok := r.ChannelChain(params.ID())
+		assert.True(t, ok)
+		assert.Equal(t, "a", chain)
+		_, err := adj.Subscribe(ctx, params.ID())
+		require.NoError(t, err)
+		assert.Equal(t, params.ID(), a.calls[len(a.calls)-1])
+
+		req := channel.AdjudicatorReq{Params: params, Tx: channel.Transaction{State: state}}
+		require.NoError(t, adj.Withdraw(ctx, req, nil))
+		assert.Equal(t, params.ID(), a.calls[len(a.calls)-1])
+		_, ok = r.ChannelChain(params.ID())
+		assert.False(t, ok, "removed after withdrawal")
+	})
+
+	t.Run("unrouted subscription", func(t *testing.T) {
+		params, _ := newChannel("stake")
+		sub, err := adj.Subscribe(ctx, params.ID())
+		require.NoError(t, err)
+		subA, subB := a.subs[len(a.subs)-1], b.subs[len(b.subs)-1]
+
+		e := channel.NewConcludedEvent(params.ID(), nil, 1)
+		subB.events <- e
+		assert.Equal(t, e, sub.Next())
+		chain, ok := r.ChannelChain(params.ID())
+		assert.True(t, ok)
+		assert.Equal(t, "b", chain)
+		assert.True(t, subA.isClosed(), "other chain closed")
+
+		require.NoError(t, sub.Close())
+		assert.Nil(t, sub.Next())
+		assert.NoError(t, sub.Err())
+		assert.True(t, subB.isClosed(), "chain closed")
+	})
+
+	t.Run("failed before routing", func(t *testing.T) {
+		params, _ := newChannel("stake")
+		sub, err := adj.Subscribe(ctx, params.ID())
+		require.NoError(t, err)
+		subA, subB := a.subs[len(a.subs)-1], b.subs[len(b.subs)-1]
+
+		subA.fail(errors.New("connection lost"))
+		e := channel.NewConcludedEvent(params.ID(), nil, 1)
+		subB.events <- e
+		assert.Equal(t, e, sub.Next())
+		chain, ok := r.ChannelChain(params.ID())
+		assert.True(t, ok)
+		assert.Equal(t, "b", chain)
+		assert.NoError(t, sub.Err())
+		require.NoError(t, sub.Close())
+	})
+
+	t.Run("all failed", func(t *testing.T) {
+		params, _ := newChannel("stake")
+		sub, err := adj.Subscribe(ctx, params.ID())
+		require.NoError(t, err)
+		subA, subB := a.subs[len(a.subs)-1], b.subs[len(b.subs)-1]
+
+		errLost := errors.New("connection lost")
+		subA.fail(errLost)
+		subB.fail(errLost)
+		assert.Nil(t, sub.Next())
+		assert.ErrorIs(t, sub.Err(), errLost)
+		_, ok := r.ChannelChain(params.ID())
+		assert.False(t, ok)
+		require.NoError(t, sub.Close())
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		params, state := newChannel("stake")
+		req := channel.AdjudicatorReq{Params: params, Tx: channel.Transaction{State: state}}
+		assert.ErrorIs(t, adj.Register(ctx, req, nil), bchannel.ErrAmbiguousRoute)
+
+		require.NoError(t, r.SetChannelChain(params.ID(), "b"))
+		require.NoError(t, adj.Register(ctx, req, nil))
+		assert.Equal(t, params.ID(), b.calls[len(b.calls)-1])
+	})
+
+	t.Run("no route", func(t *testing.T) {
+		params, state := newChannel("atom", "osmo")
+		assert.ErrorIs(t, f.Fund(ctx, channel.FundingReq{Params: params, State: state}), bchannel.ErrNoRoute)
+		assert.ErrorIs(t, r.SetChannelChain(params.ID(), "c"), bchannel.ErrNoRoute)
+
+		noAdj, err := bchannel.NewRouter(bchannel.Chain{ID: "a", Funder: a})
+		require.NoError(t, err)
+		_, err = bchannel.NewMultiAdjudicator(noAdj).Subscribe(ctx, params.ID())
+		assert.ErrorIs(t, err, bchannel.ErrNoRoute)
+	})
+
+	t.Run("invalid chains", func(t *testing.T) {
+		_, err := bchannel.NewRouter(bchannel.Chain{ID: "a"}, bchannel.Chain{ID: "a"})
+		assert.Error(t, err)
+		_, err = bchannel.NewRouter(bchannel.Chain{})
+		assert.Error(t, err)
+	})
+}
+
+// routedSetup is an adjudicator setup whose channels are opened on one of
+// several chains and that uses a multi-chain funder and adjudicator.
+type routedSetup struct {
+	*adjudicatorSetup // Chain on which channels are opened.
+	chain             string
+	router            *bchannel.Router
+	funder            channel.Funder
+	multi             channel.Adjudicator
+}
+
+// Adjudicator returns the multi-chain adjudicator. As the conformance tests
+// withdraw for all participants with the same adjudicator, while the router
+// removes a channel after its first withdrawal, the channel is registered
+// again before each withdrawal.
+func (s *routedSetup) Adjudicator() channel.Adjudicator {
+	return &reroutingAdjudicator{Adjudicator: s.multi, s: s}
+}
+
+type reroutingAdjudicator struct {
+	channel.Adjudicator
+	s *routedSetup
+}
+
+func (a *reroutingAdjudicator) Withdraw(ctx context.Context, req channel.AdjudicatorReq, subStates channel.StateMap) error {
+	if err := a.s.router.SetChannelChain(req.Params.ID(), a.s.chain); err != nil {
+		return err
+	}
+	return a.Adjudicator.Withdraw(ctx, req, subStates)
+}
+
+func (s *routedSetup) NewFundedChannel(ctx context.Context, rng *rand.Rand) (channel.Params, channel.State) {
+	opts := []ctest.RandomOpt{ctest.WithoutApp(), ctest.WithIsFinal(false), ctest.WithVersion(0)}
+	params, state := s.r.NewParamsAndState(rng, opts...)
+	if err := s.router.SetChannelChain(params.ID(), s.chain); err != nil {
+		panic(err)
+	}
+
+	requests := make([]*channel.FundingReq, len(params.Parts))
+	for i := range requests {
+		requests[i] = newFundingRequest(ctx, params, state, channel.Index(i), s.c)
+	}
+	if err := fundAll(ctx, s.funder, requests); err != nil {
+		panic(err)
+	}
+	return *params, *state
+}
+
+// TestMultiAdjudicator runs the adjudicator tests on two chains through a
+// multi-chain funder and adjudicator.
+func TestMultiAdjudicator(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	ids := []string{"chain-a", "chain-b"}
+	setups := make([]*adjudicatorSetup, len(ids))
+	chains := make([]bchannel.Chain, len(ids))
+	for i, id := range ids {
+		c, contract := test.NewTestClientWithContract(ctx, t)
+		c.StartTicking(blockTick, simChainTick)
+		defer c.StopTicking()
+
+		setups[i] = newAdjudicatorSetup(ctx, c, c, contract)
+		f, err := bchannel.NewFunder(ctx, c, contract, c.Account(), bchannel.FunderPollingIntervalOpt(polling))
+		require.NoError(t, err)
+		chains[i] = bchannel.Chain{ID: id, Funder: f, Adjudicator: setups[i].adj}
+	}
+	r, err := bchannel.NewRouter(chains...)
+	require.NoError(t, err)
+
+	for i, id := range ids {
+		t.Run(id, func(t *testing.T) {
+			ptest.TestAdjudicatorWithSubscription(ctx, t, rng, &routedSetup{
+				adjudicatorSetup: setups[i],
+				chain:            id,
+				router:           r,
+				funder:           bchannel.NewMultiFunder(r),
+				multi:            bchannel.NewMultiAdjudicator(r),
+			})
+		})
+	}
+}
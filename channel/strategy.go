@@ -0,0 +1,131 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel
+
+import (
+	"github.com/cosmos/cosmos-sdk/types"
+	"perun.network/go-perun/channel"
+)
+
+// FundingStrategy determines when a participant deposits its share of the
+// funding of a channel.
+//
+// Strategies that wait for other participants before depositing trade
+// counterparty risk for liveness: if all participants of a channel fund
+// egoistically, none of them deposits and funding times out.
+type FundingStrategy struct {
+	kind  fundingStrategyKind
+	limit types.Coins
+}
+
+type fundingStrategyKind int
+
+const (
+	fundConcurrently fundingStrategyKind = iota
+	fundEgoistically
+	fundInOrder
+	fundCappedFirst
+)
+
+// ConcurrentFundingStrategy returns the strategy that is used by default. A
+// participant deposits its share immediately.
+func ConcurrentFundingStrategy() FundingStrategy {
+	return FundingStrategy{kind: fundConcurrently}
+}
+
+// EgoisticFundingStrategy returns a strategy with which a participant only
+// deposits its share once the deposits of all other participants are
+// complete.
+func EgoisticFundingStrategy() FundingStrategy {
+	return FundingStrategy{kind: fundEgoistically}
+}
+
+// OrderedFundingStrategy returns a strategy with which participants deposit
+// in the order of their indices. A participant deposits its share once the
+// deposits of all participants with lower indices are complete.
+func OrderedFundingStrategy() FundingStrategy {
+	return FundingStrategy{kind: fundInOrder}
+}
+
+// CappedFundingStrategy returns a strategy with which a participant first
+// deposits its share capped at the given limit. It deposits the rest of its
+// share once every other participant has deposited its share up to the same
+// limit. Assets without a limit are only deposited in the second step.
+//
+// If all participants use the strategy with the same limit, the amount that a
+// participant risks losing to a counterparty that does not fund is bounded
+// by the limit.
+func CappedFundingStrategy(limit types.Coins) FundingStrategy {
+	return FundingStrategy{kind: fundCappedFirst, limit: limit}
+}
+
+// fundingPlan describes the deposits of a participant. The participant first
+// deposits first. If await is not nil, it then waits until the deposit of each
+// awaited participant covers the participant's awaited share and deposits the
+// rest of its share.
+type fundingPlan struct {
+	first types.Coins
+	await map[channel.Index]types.Coins
+}
+
+// plan returns the funding plan of the participant of the request, whose
+// share is funds.
+func (s FundingStrategy) plan(req *fundingReq, funds types.Coins) (fundingPlan, error) {
+	var (
+		await []channel.Index
+		share func(types.Coins) types.Coins // The awaited share of a participant.
+	)
+	switch s.kind {
+	case fundConcurrently:
+		return fundingPlan{first: funds}, nil
+	case fundEgoistically:
+		await = req.others()
+		share = func(c types.Coins) types.Coins { return c }
+	case fundInOrder:
+		await = make([]channel.Index, 0, req.Idx)
+		for i := channel.Index(0); i < req.Idx; i++ {
+			await = append(await, i)
+		}
+		share = func(c types.Coins) types.Coins { return c }
+	case fundCappedFirst:
+		await = req.others()
+		share = func(c types.Coins) types.Coins { return capCoins(c, s.limit) }
+	}
+
+	shares := make(map[channel.Index]types.Coins, len(await))
+	for _, i := range await {
+		funds, err := req.FundsForPart(i)
+		if err != nil {
+			return fundingPlan{}, err
+		}
+		shares[i] = share(funds)
+	}
+	first := types.NewCoins()
+	if s.kind == fundCappedFirst {
+		first = capCoins(funds, s.limit)
+	}
+	return fundingPlan{first: first, await: shares}, nil
+}
+
+// capCoins returns the coins capped at the given limit. Coins whose
+// denomination has no limit are dropped.
+func capCoins(coins, limit types.Coins) types.Coins {
+	capped := types.NewCoins()
+	for _, c := range coins {
+		amount := types.MinInt(c.Amount, limit.AmountOf(c.Denom))
+		capped = capped.Add(types.NewCoin(c.Denom, amount))
+	}
+	return capped
+}
@@ -0,0 +1,215 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/types"
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	pchannel "github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"perun.network/go-perun/channel"
+	ctest "perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+// deposit is a deposit recorded by a depositRecorder.
+type deposit struct {
+	fID    string // Hex-encoded funding ID.
+	sender string
+	funds  types.Coins
+}
+
+// depositRecorder is a client that records the deposits that are executed
+// successfully, in order.
+type depositRecorder struct {
+	client.Client
+	mu       sync.Mutex
+	deposits []deposit
+}
+
+func (r *depositRecorder) ExecuteContract(ctx context.Context, in *wtypes.MsgExecuteContract, opts ...grpc.CallOption) (*wtypes.MsgExecuteContractResponse, error) {
+	// Executing under the lock ensures that a deposit is recorded before
+	// other funders can observe it.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	resp, err := r.Client.ExecuteContract(ctx, in, opts...)
+	var msg binding.DepositExecuteMsg
+	if err != nil || json.Unmarshal(in.Msg, &msg) != nil || msg.Deposit == nil {
+		return resp, err
+	}
+	r.deposits = append(r.deposits, deposit{fID: hex.EncodeToString(msg.Deposit), sender: in.Sender, funds: in.Funds})
+	return resp, err
+}
+
+// recorded returns the recorded deposits and resets the recording.
+func (r *depositRecorder) recorded() []deposit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.deposits
+	r.deposits = nil
+	return d
+}
+
+// TestFunderStrategies tests that the funding strategies deposit in the
+// expected order.
+func TestFunderStrategies(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	rec := &depositRecorder{Client: c}
+	gen := test.NewRandomGenerator(maxNumParts, maxNumAssets, big.NewInt(maxFundingAmount), int64(maxChallengeDuration.Seconds()))
+	const numParts = 3
+
+	// fund funds a new channel with a funder per participant using the
+	// given strategies. It returns the funding IDs of the participants and
+	// the first funding error.
+	fund := func(ctx context.Context, strategies []bchannel.FundingStrategy, opts ...ctest.RandomOpt) ([]string, error) {
+		opts = append([]ctest.RandomOpt{ctest.WithNumParts(numParts), ctest.WithBalancesInRange(big.NewInt(100), big.NewInt(maxFundingAmount))}, opts...)
+		params, state := gen.NewParamsAndState(rng, opts...)
+		fIDs := make([]string, numParts)
+		errs := make(chan error, numParts)
+		for i, s := range strategies {
+			f, err := bchannel.NewFunder(ctx, rec, contract, c.Account(), bchannel.FunderPollingIntervalOpt(polling), bchannel.FunderStrategyOpt(s))
+			require.NoError(t, err)
+			fID, err := binding.CalcFundingID(params.ID(), params.Parts[i])
+			require.NoError(t, err)
+			fIDs[i] = hex.EncodeToString(fID)
+
+			req := newFundingRequest(ctx, params, state, channel.Index(i), c)
+			go func(req channel.FundingReq) { errs <- f.Fund(ctx, req) }(*req)
+		}
+		for range strategies {
+			if err := <-errs; err != nil {
+				return fIDs, err
+			}
+		}
+		return fIDs, nil
+	}
+	concurrent := bchannel.ConcurrentFundingStrategy()
+
+	t.Run("egoistic", func(t *testing.T) {
+		fIDs, err := fund(ctx, []bchannel.FundingStrategy{bchannel.EgoisticFundingStrategy(), concurrent, concurrent})
+		require.NoError(t, err)
+		deposits := rec.recorded()
+		require.Len(t, deposits, numParts)
+		assert.Equal(t, fIDs[0], deposits[numParts-1].fID, "egoistic participant deposits last")
+	})
+
+	t.Run("ordered", func(t *testing.T) {
+		ordered := bchannel.OrderedFundingStrategy()
+		fIDs, err := fund(ctx, []bchannel.FundingStrategy{ordered, ordered, ordered})
+		require.NoError(t, err)
+		deposits := rec.recorded()
+		require.Len(t, deposits, numParts)
+		for i, d := range deposits {
+			assert.Equal(t, fIDs[i], d.fID, "deposit %d", i)
+		}
+	})
+
+	t.Run("capped", func(t *testing.T) {
+		limit := types.NewCoins(types.NewInt64Coin("atom", 50), types.NewInt64Coin("osmo", 50))
+		capped := bchannel.CappedFundingStrategy(limit)
+		assets := ctest.WithAssets(binding.Asset("atom"), binding.Asset("osmo"))
+		fIDs, err := fund(ctx, []bchannel.FundingStrategy{capped, capped, capped}, assets)
+		require.NoError(t, err)
+		deposits := rec.recorded()
+		require.Len(t, deposits, 2*numParts)
+		for i, d := range deposits[:numParts] {
+			assert.True(t, limit.IsEqual(d.funds), "deposit %d is capped", i)
+		}
+		for i, fID := range fIDs {
+			n := 0
+			for _, d := range deposits {
+				if d.fID == fID {
+					n++
+				}
+			}
+			assert.Equal(t, 2, n, "deposits of participant %d", i)
+		}
+	})
+
+	t.Run("over-deposit", func(t *testing.T) {
+		// P1 deposits double its share and P2 deposits nothing. The excess of
+		// P1 must not make up for P2.
+		limit := types.NewCoins(types.NewInt64Coin("atom", 50), types.NewInt64Coin("osmo", 50))
+		for name, s := range map[string]bchannel.FundingStrategy{
+			"egoistic": bchannel.EgoisticFundingStrategy(),
+			"capped":   bchannel.CappedFundingStrategy(limit),
+		} {
+			t.Run(name, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(ctx, time.Second)
+				defer cancel()
+				params, state := gen.NewParamsAndState(rng,
+					ctest.WithNumParts(numParts),
+					ctest.WithAssets(binding.Asset("atom"), binding.Asset("osmo")),
+					ctest.WithBalancesInRange(big.NewInt(100), big.NewInt(maxFundingAmount)))
+				// With equal shares, the deposit of P1 covers the shares of
+				// P1 and P2 in sum.
+				for _, bals := range state.Balances {
+					bals[2].Set(bals[1])
+				}
+
+				share, err := binding.MakeCoins(state.Assets, pchannel.Balances(state.Balances).ForPart(1))
+				require.NoError(t, err)
+				double := share.Add(share...)
+				require.NoError(t, c.AddCoins(ctx, c.Account(), double))
+				fID, err := binding.CalcFundingID(params.ID(), params.Parts[1])
+				require.NoError(t, err)
+				msg, err := binding.NewDepositExecuteMsg(fID)
+				require.NoError(t, err)
+				_, err = c.ExecuteContract(ctx, &wtypes.MsgExecuteContract{
+					Sender:   c.Account().String(),
+					Contract: contract.Address(),
+					Msg:      msg,
+					Funds:    double,
+				})
+				require.NoError(t, err)
+
+				f, err := bchannel.NewFunder(ctx, rec, contract, c.Account(), bchannel.FunderPollingIntervalOpt(polling), bchannel.FunderStrategyOpt(s))
+				require.NoError(t, err)
+				req := newFundingRequest(ctx, params, state, 0, c)
+				assert.ErrorIs(t, f.Fund(ctx, *req), context.DeadlineExceeded)
+				for _, d := range rec.recorded() {
+					assert.True(t, d.funds.IsAllLTE(limit), "participant only deposits its capped share")
+				}
+			})
+		}
+	})
+
+	t.Run("all egoistic", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		egoistic := bchannel.EgoisticFundingStrategy()
+		_, err := fund(ctx, []bchannel.FundingStrategy{egoistic, egoistic, egoistic})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Empty(t, rec.recorded(), "no participant deposits")
+	})
+}
@@ -16,10 +16,14 @@ package channel
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/perun-network/perun-cosmwasm-backend/channel/binding"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/log"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/metrics"
 	"perun.network/go-perun/channel"
 )
 
@@ -31,6 +35,7 @@ type EventSubscription struct {
 	err         chan error
 	prev        binding.DisputeQueryResponse
 	once        sync.Once
+	log         log.Logger
 }
 
 func NewEventSubscription(a *Adjudicator, ch channel.ID) *EventSubscription {
@@ -40,6 +45,7 @@ func NewEventSubscription(a *Adjudicator, ch channel.ID) *EventSubscription {
 		closed:      make(chan struct{}),
 		err:         make(chan error, 1),
 		prev:        binding.DisputeQueryResponse{},
+		log:         a.log.WithField(log.ChannelIDKey, hex.EncodeToString(ch[:])),
 	}
 }
 
@@ -54,13 +60,19 @@ func (s *EventSubscription) Next() channel.AdjudicatorEvent {
 
 	go func() {
 		for {
+			s.adjudicator.metrics.Poll(metrics.ComponentSubscription)
 			d, err := s.readState(ctx)
-			if err != nil && err.Error() != "Unknown dispute: query wasm contract failed" {
+			switch {
+			case err == nil, errors.Is(err, binding.ErrUnknownDispute):
+			case s.adjudicator.retry.classify(err) != ErrorPermanent:
+				// The error persisted after retrying, try again with the next poll.
+				s.log.WithError(err).Warnf("Reading dispute failed")
+			default:
 				errChan <- err
 				return
 			}
 
-			if !d.Equal(s.prev) {
+			if err == nil && !d.Equal(s.prev) {
 				s.prev = d
 				eventChan <- s.makeEvent(d)
 				return
@@ -103,27 +115,18 @@ func (s *EventSubscription) Close() error {
 }
 
 func (s *EventSubscription) readState(ctx context.Context) (binding.DisputeQueryResponse, error) {
-	ch := s.channelID
-	q, err := binding.NewDisputeQueryMsg(ch)
-	if err != nil {
-		return binding.DisputeQueryResponse{}, err
-	}
-
-	resp, err := s.adjudicator.Query(ctx, q)
-	if err != nil {
-		return binding.DisputeQueryResponse{}, err
-	}
-
-	return binding.DecodeDisputeQueryResponse(resp.Data)
+	return s.adjudicator.queryDispute(ctx, s.channelID)
 }
 
 func (s *EventSubscription) makeEvent(d binding.DisputeQueryResponse) channel.AdjudicatorEvent {
 	state := d.State.PerunState()
 	cID := state.ID
 	v := state.Version
-	timeout := makeTimeout(s.adjudicator.client, d.Timeout(), s.adjudicator.polling)
+	timeout := NewTimeout(s.adjudicator.blocks, d.Timeout())
 	if d.Concluded {
+		s.adjudicator.metrics.Event(metrics.EventConcluded)
 		return channel.NewConcludedEvent(cID, timeout, v)
 	}
+	s.adjudicator.metrics.Event(metrics.EventRegistered)
 	return channel.NewRegisteredEvent(cID, timeout, v, state, nil)
 }
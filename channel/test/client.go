@@ -30,12 +30,13 @@ import (
 func NewTestClientWithContract(ctx context.Context, t *testing.T) (*simulation.Client, client.ContractInstance) {
 	c := simulation.NewTestClient(t)
 
-	contractTemplate := client.NewContractTemplate(
+	contractTemplate, err := client.NewContractTemplate(
 		contract.Code,
 		contract.InitMsgSchema,
 		contract.ExecuteMsgSchema,
 		contract.QueryMsgSchema,
 	)
+	require.NoError(t, err, "create contract template")
 
 	storedContract, err := c.StoreContractTemplate(ctx, contractTemplate)
 	require.NoError(t, err, "store contract")
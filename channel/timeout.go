@@ -16,52 +16,152 @@ package channel
 
 import (
 	"context"
-	"log"
+	"sync"
 	"time"
 
-	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
-	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	tmtypes "github.com/tendermint/tendermint/proto/tendermint/types"
 )
 
-// Timeout represents a timeout that is bound to block time.
+// Timeout represents a timeout that is bound to block time or block height.
+//
+// A time-based timeout elapses with the first block whose time is after the
+// timeout time. A height-based timeout elapses with the block at the timeout
+// height.
 type Timeout struct {
-	c       client.Client
-	t       time.Time
-	polling time.Duration
+	blocks *BlockWatcher
+	t      time.Time
+	height int64 // The height at which the timeout elapses, or 0 if unknown.
+	before int64 // The latest height seen at which a time-based timeout has not elapsed.
+	after  int64 // The earliest height seen at which a time-based timeout has elapsed.
+	mu     sync.Mutex
+}
+
+// NewTimeout creates a timeout that elapses after the given block time.
+func NewTimeout(blocks *BlockWatcher, t time.Time) *Timeout {
+	return &Timeout{
+		blocks: blocks,
+		t:      t,
+	}
 }
 
-func makeTimeout(
-	c client.Client,
-	t time.Time,
-	polling time.Duration,
-) *Timeout {
+// NewHeightTimeout creates a timeout that elapses at the given block height.
+func NewHeightTimeout(blocks *BlockWatcher, height int64) *Timeout {
 	return &Timeout{
-		c:       c,
-		t:       t,
-		polling: polling,
+		blocks: blocks,
+		height: height,
 	}
 }
 
+// Height returns the block height at which the timeout elapses. For
+// time-based timeouts, this is the height of the first block whose time is
+// after the timeout time. It is only known after the timeout has been
+// observed to be elapsed. If the block watcher skipped blocks, the blocks
+// in between are queried to find it. The second return value is false if
+// the height is not known yet or could not be determined, e.g., because the
+// blocks before it have been pruned.
+func (t *Timeout) Height() (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.height, t.height != 0
+}
+
 // IsElapsed should return whether the timeout has elapsed at the time of
 // the call of this method.
 func (t *Timeout) IsElapsed(ctx context.Context) bool {
-	resp, err := t.c.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	h, err := t.blocks.Latest(ctx)
 	if err != nil {
-		log.Printf("Warning: Error getting latest block: %v\n", err)
+		t.blocks.log.WithError(err).Warnf("Getting latest block failed")
 		return false
 	}
-	return resp.Block.Header.Time.After(t.t)
+	if !t.isElapsedAt(h) {
+		return false
+	}
+	t.findHeight(ctx)
+	return true
 }
 
 // Wait waits for the timeout to elapse. If the context is canceled, Wait
 // should return immediately with the context's error.
-func (t *Timeout) Wait(ctx context.Context) error {
-	for !t.IsElapsed(ctx) {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(t.polling):
-		}
+func (t *Timeout) Wait(ctx context.Context) (err error) {
+	ctx, span := t.blocks.tracer.Start(ctx, SpanTimeoutWait)
+	defer func() { endSpan(span, err) }()
+
+	if t.IsElapsed(ctx) {
+		return nil
+	}
+	if err := t.blocks.Wait(ctx, t.isElapsedAt); err != nil {
+		return err
 	}
+	t.findHeight(ctx)
 	return nil
 }
+
+// isElapsedAt returns whether the timeout is elapsed at the given block.
+func (t *Timeout) isElapsedAt(h tmtypes.Header) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.t.IsZero() {
+		return h.Height >= t.height
+	}
+	t.observe(h)
+	return t.after != 0
+}
+
+// observe records whether the time-based timeout has elapsed at the given
+// block. The height of the timeout is known once the blocks before and at it
+// have been seen. The caller must hold the lock.
+func (t *Timeout) observe(h tmtypes.Header) {
+	if h.Time.After(t.t) {
+		if t.after == 0 || h.Height < t.after {
+			t.after = h.Height
+		}
+	} else if h.Height > t.before {
+		t.before = h.Height
+	}
+	if t.after == t.before+1 {
+		t.height = t.after
+	}
+}
+
+// findHeight determines the height of an elapsed time-based timeout by
+// querying the blocks between the latest block seen before the timeout time
+// and the earliest block seen after it. If no block before the timeout time
+// has been seen, the search steps back in growing steps. Blocks that cannot
+// be queried while stepping back, e.g., because they have been pruned, bound
+// the search from below. Otherwise, failed queries are logged and leave the
+// height unknown.
+func (t *Timeout) findHeight(ctx context.Context) {
+	var floor int64 // The latest height known to be unavailable.
+	for step := int64(1); ; step *= 2 {
+		t.mu.Lock()
+		seen, after, known := t.before, t.after, t.height != 0
+		t.mu.Unlock()
+		before := seen
+		if before < floor {
+			before = floor
+		}
+		if known || before+1 >= after {
+			return // The height is known or cannot be determined.
+		}
+
+		height := before + (after-before)/2
+		if before == 0 {
+			height = after - step
+			if height < 1 {
+				height = 1
+			}
+		}
+		h, err := t.blocks.headerAt(ctx, height)
+		if err != nil && seen != 0 {
+			t.blocks.log.WithError(err).Warnf("Getting block at height %d failed", height)
+			return
+		} else if err != nil {
+			floor = height
+			continue
+		}
+		t.mu.Lock()
+		t.observe(h)
+		t.mu.Unlock()
+	}
+}
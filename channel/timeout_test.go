@@ -0,0 +1,98 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/simulation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTimeout tests time-based and height-based timeouts sharing a block
+// watcher.
+func TestTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	c := simulation.NewTestClient(t)
+	c.StartTicking(blockTick, simChainTick)
+	defer c.StopTicking()
+
+	resp, err := c.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	require.NoError(t, err, "get latest block")
+	h := resp.Block.Header
+
+	w := bchannel.NewBlockWatcher(c, blockTick)
+	timeTimeout := bchannel.NewTimeout(w, h.Time.Add(3*simChainTick))
+	heightTimeout := bchannel.NewHeightTimeout(w, h.Height+3)
+
+	_, ok := timeTimeout.Height()
+	assert.False(t, ok, "height unknown before elapsed")
+	height, ok := heightTimeout.Height()
+	assert.True(t, ok, "height known")
+	assert.Equal(t, h.Height+3, height, "height")
+
+	var wg sync.WaitGroup
+	for _, timeout := range []*bchannel.Timeout{timeTimeout, heightTimeout} {
+		wg.Add(1)
+		go func(timeout *bchannel.Timeout) {
+			defer wg.Done()
+			assert.NoError(t, timeout.Wait(ctx), "wait")
+			assert.True(t, timeout.IsElapsed(ctx), "elapsed")
+		}(timeout)
+	}
+	wg.Wait()
+
+	height, ok = timeTimeout.Height()
+	assert.True(t, ok, "height known after elapsed")
+	assert.Greater(t, height, h.Height+2, "height")
+}
+
+// TestTimeoutHeight tests that the height of a time-based timeout is the
+// height of the first block after the timeout time, even if the timeout is
+// only observed several blocks later.
+func TestTimeoutHeight(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	c := simulation.NewTestClient(t)
+	resp, err := c.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	require.NoError(t, err, "get latest block")
+	h := resp.Block.Header
+
+	const numBlocks = 10
+	for elapsedAt := int64(1); elapsedAt <= numBlocks; elapsedAt++ {
+		// A new watcher does not return a cached header.
+		w := bchannel.NewBlockWatcher(c, blockTick)
+		timeout := bchannel.NewTimeout(w, h.Time.Add(time.Duration(elapsedAt)*simChainTick-time.Second))
+		for i := 1; i <= numBlocks; i++ {
+			c.SetBlockTime(c.BlockTime().Add(simChainTick))
+		}
+		require.True(t, timeout.IsElapsed(ctx), "elapsed")
+
+		height, ok := timeout.Height()
+		require.True(t, ok, "height known")
+		assert.Equal(t, h.Height+elapsedAt, height, "height")
+		h.Time = c.BlockTime()
+		h.Height += numBlocks
+	}
+}
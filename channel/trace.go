@@ -0,0 +1,72 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel
+
+import (
+	"encoding/hex"
+
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"perun.network/go-perun/channel"
+)
+
+// TracerName is the name of the tracer used by this package.
+const TracerName = "github.com/perun-network/perun-cosmwasm-backend/channel"
+
+// Names of the spans created by this package.
+const (
+	SpanFund          = "Funder.Fund"
+	SpanDeposit       = "Funder.deposit"
+	SpanPoll          = "Funder.poll"
+	SpanRegister      = "Adjudicator.Register"
+	SpanWithdraw      = "Adjudicator.Withdraw"
+	SpanConclude      = "Adjudicator.conclude"
+	SpanWithdrawFunds = "Adjudicator.withdraw"
+	SpanMessage       = "Adjudicator.message"
+	SpanSign          = "Adjudicator.sign"
+	SpanBroadcast     = "Contract.broadcast"
+	SpanConfirm       = "Contract.confirm"
+	SpanTimeoutWait   = "Timeout.Wait"
+)
+
+// Keys of span attributes that have no corresponding log field.
+const (
+	versionKey = "version"
+	landedKey  = "landed"
+)
+
+// defaultTracer returns the tracer of OpenTelemetry's global tracer provider. As
+// the global provider delegates to the provider that is set last, the
+// provider may be set after the backend has been created.
+func defaultTracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// channelIDAttribute returns the span attribute for the given channel ID.
+func channelIDAttribute(id channel.ID) attribute.KeyValue {
+	return attribute.String(log.ChannelIDKey, hex.EncodeToString(id[:]))
+}
+
+// endSpan records the error, if not nil, and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
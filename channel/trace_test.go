@@ -0,0 +1,127 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"perun.network/go-perun/channel"
+	ctest "perun.network/go-perun/channel/test"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+// TestTracing tests the span tree of a channel lifecycle: funding,
+// registering, waiting for the timeout, and withdrawing.
+func TestTracing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	c.StartTicking(blockTick, simChainTick)
+	defer c.StopTicking()
+
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	a := newAdjudicatorSetup(ctx, c, c, contract, bchannel.AdjudicatorTracerProviderOpt(tp))
+	f, err := bchannel.NewFunder(ctx, c, contract, c.Account(),
+		bchannel.FunderPollingIntervalOpt(polling),
+		bchannel.FunderTracerProviderOpt(tp))
+	require.NoError(t, err)
+
+	// The root span stands in for the span of the calling framework.
+	ctx, root := tp.Tracer("test").Start(ctx, "lifecycle")
+	params, state := a.r.NewParamsAndState(rng, ctest.WithoutApp(), ctest.WithNumParts(1), ctest.WithIsFinal(false), ctest.WithVersion(0))
+	require.NoError(t, f.Fund(ctx, *newFundingRequest(ctx, params, state, 0, c)))
+
+	sub, err := a.adj.Subscribe(ctx, params.ID())
+	require.NoError(t, err)
+	defer sub.Close()
+	req := channel.AdjudicatorReq{
+		Params: params,
+		Acc:    a.Account(params.Parts[0]),
+		Tx: channel.Transaction{
+			State: state,
+			Sigs:  a.SignState(state, params.Parts),
+		},
+	}
+	require.NoError(t, a.adj.Register(ctx, req, nil))
+	e := sub.Next()
+	require.NotNil(t, e, "registered event")
+	require.NoError(t, e.Timeout().Wait(ctx))
+	require.NoError(t, a.adj.Withdraw(ctx, req, nil))
+	root.End()
+
+	spans := exp.GetSpans()
+	// children returns the names of the direct children of the first span
+	// with the given name, in the order in which they started.
+	children := func(name string) []string {
+		var parent trace.SpanID
+		for _, s := range spans {
+			if s.Name == name {
+				parent = s.SpanContext.SpanID()
+				break
+			}
+		}
+		require.True(t, parent.IsValid(), "span %s", name)
+
+		var cs []tracetest.SpanStub
+		for _, s := range spans {
+			if s.Parent.SpanID() == parent {
+				cs = append(cs, s)
+			}
+		}
+		sort.SliceStable(cs, func(i, j int) bool { return cs[i].StartTime.Before(cs[j].StartTime) })
+		names := make([]string, len(cs))
+		for i, s := range cs {
+			names[i] = s.Name
+		}
+		return names
+	}
+	// dedup removes consecutive duplicates, so that repeated polls do not
+	// make the test depend on timing.
+	dedup := func(names []string) []string {
+		var res []string
+		for _, n := range names {
+			if len(res) == 0 || res[len(res)-1] != n {
+				res = append(res, n)
+			}
+		}
+		return res
+	}
+
+	assert.Equal(t, []string{bchannel.SpanFund, bchannel.SpanRegister, bchannel.SpanTimeoutWait, bchannel.SpanWithdraw}, dedup(children("lifecycle")))
+	assert.Equal(t, []string{bchannel.SpanDeposit, bchannel.SpanPoll}, dedup(children(bchannel.SpanFund)))
+	assert.Equal(t, []string{bchannel.SpanBroadcast}, children(bchannel.SpanDeposit))
+	assert.Equal(t, []string{bchannel.SpanMessage, bchannel.SpanBroadcast}, children(bchannel.SpanRegister))
+	assert.Equal(t, []string{bchannel.SpanConclude, bchannel.SpanWithdrawFunds}, children(bchannel.SpanWithdraw))
+	assert.Equal(t, []string{bchannel.SpanMessage, bchannel.SpanBroadcast}, children(bchannel.SpanConclude))
+	assert.Equal(t, []string{bchannel.SpanSign, bchannel.SpanMessage, bchannel.SpanBroadcast}, children(bchannel.SpanWithdrawFunds))
+
+	for _, s := range spans {
+		if s.Name == bchannel.SpanBroadcast {
+			assert.Len(t, s.Attributes, 2, "transaction hash and height")
+		}
+	}
+}
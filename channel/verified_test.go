@@ -0,0 +1,96 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"testing"
+
+	bchannel "github.com/perun-network/perun-cosmwasm-backend/channel"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/proof"
+	ptest "github.com/perun-network/perun-cosmwasm-backend/pkg/perun/channel/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	pkgtest "perun.network/go-perun/pkg/test"
+)
+
+// TestAdjudicatorVerified runs the adjudicator tests with verified queries.
+func TestAdjudicatorVerified(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	// Store proofs are enabled by the querier. Until the state is committed,
+	// verified queries are retried.
+	c.StartTicking(slowBlockTick, slowChainTick) // Verified reads lag a block.
+	defer c.StopTicking()
+	opt := bchannel.AdjudicatorVerifiedQueriesOpt(proof.NewQuerier(c, c))
+	ptest.TestAdjudicatorWithSubscription(ctx, t, rng, newAdjudicatorSetup(ctx, c, c, contract, opt))
+}
+
+// hidingClient is a proof client of a malicious node that hides all state.
+type hidingClient struct {
+	proof.Client
+}
+
+func (c hidingClient) QueryStoreProof(ctx context.Context, storeName string, key []byte, height int64) (proof.StoreProof, error) {
+	p, err := c.Client.QueryStoreProof(ctx, storeName, key, height)
+	p.Value = nil
+	return p, err
+}
+
+// TestFunderVerifiedHiddenState tests that a verified query detects a node
+// that hides a deposit.
+func TestFunderVerifiedHiddenState(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	rng := pkgtest.Prng(t)
+	c, contract := test.NewTestClientWithContract(ctx, t)
+	c.EnableStoreProofs()
+	a := newAdjudicatorSetup(ctx, c, c, contract)
+	params, state := a.NewFundedChannel(ctx, rng)
+	c.SetBlockTime(c.BlockTime().Add(simChainTick)) // Commit the deposits.
+
+	newFunder := func(pc proof.Client) *bchannel.Funder {
+		q := proof.NewQuerier(pc, c)
+		f, err := bchannel.NewFunder(ctx, c, contract, c.Account(),
+			bchannel.FunderPollingIntervalOpt(polling),
+			bchannel.FunderVerifiedQueriesOpt(q),
+			bchannel.FunderRetryPolicyOpt(bchannel.NoRetryPolicy()))
+		require.NoError(t, err)
+		return f
+	}
+	req := *newFundingRequest(ctx, &params, &state, 0, c)
+
+	err := newFunder(hidingClient{c}).Fund(ctx, req)
+	assert.ErrorIs(t, err, proof.ErrInvalidProof)
+
+	// The honest node shows the complete funding and nothing is deposited.
+	require.NoError(t, newFunder(c).Fund(ctx, withoutFunds(req)))
+}
+
+// withoutFunds returns a copy of the funding request in which the funded
+// participant agreed to deposit nothing.
+func withoutFunds(req channel.FundingReq) channel.FundingReq {
+	req.Agreement = req.Agreement.Clone()
+	for _, bals := range req.Agreement {
+		bals[req.Idx].SetInt64(0)
+	}
+	return req
+}
@@ -120,9 +120,15 @@ func newRoleSetups(t *testing.T, rng *rand.Rand, names [2]string, asset channel.
 
 	// Create adjudicator.
 	adjOpt := bchannel.AdjudicatorPollingIntervalOpt(polling)
-	adj := bchannel.NewAdjudicator(c, contract, c.Account(), adjOpt)
+	adj, err := bchannel.NewAdjudicator(ctx, c, contract, c.Account(), adjOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
 	funderOpt := bchannel.FunderPollingIntervalOpt(polling)
-	funder := bchannel.NewFunder(c, contract, c.Account(), funderOpt)
+	funder, err := bchannel.NewFunder(ctx, c, contract, c.Account(), funderOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	for i := 0; i < n; i++ {
 		setup[i] = ctest.RoleSetup{
@@ -136,8 +142,11 @@ func newRoleSetups(t *testing.T, rng *rand.Rand, names [2]string, asset channel.
 			ChallengeDuration: challengeDuration,
 		}
 
-		coins := binding.MakeCoins([]channel.Asset{asset}, []channel.Bal{amounts[i]})
-		err := c.AddCoins(ctx, c.Account(), coins)
+		coins, err := binding.MakeCoins([]channel.Asset{asset}, []channel.Bal{amounts[i]})
+		if err != nil {
+			panic(err)
+		}
+		err = c.AddCoins(ctx, c.Account(), coins)
 		if err != nil {
 			panic(err)
 		}
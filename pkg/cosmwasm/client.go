@@ -15,13 +15,32 @@
 package cosmwasm
 
 import (
+	"context"
+
 	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
 	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
 )
 
+// Headers in which a client may report details of an executed transaction.
+// The height of the block containing the transaction may be reported in the
+// header grpctypes.GRPCBlockHeightHeader.
+const (
+	TxHashHeader  = "x-cosmos-tx-hash"  // Hash of the transaction.
+	GasUsedHeader = "x-cosmos-gas-used" // Gas used by the transaction.
+)
+
 // Client provides methods for interacting with a CosmWasm ledger.
 type Client interface {
 	wtypes.MsgClient
 	wtypes.QueryClient
 	tmservice.ServiceClient
 }
+
+// Simulator simulates contract executions without applying them.
+type Simulator interface {
+	// SimulateExecuteContracts simulates executing the messages in the given
+	// order, each on the state resulting from the previous ones, and returns
+	// the gas used by each of them. Implementations may include the fixed
+	// costs of the transaction in the gas used by the first message.
+	SimulateExecuteContracts(ctx context.Context, msgs []*wtypes.MsgExecuteContract) ([]uint64, error)
+}
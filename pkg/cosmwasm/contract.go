@@ -44,23 +44,38 @@ type (
 
 type contractTemplate struct {
 	code        []byte
-	initSchema  gojsonschema.JSONLoader
-	execSchema  gojsonschema.JSONLoader
-	querySchema gojsonschema.JSONLoader
+	initSchema  *gojsonschema.Schema
+	execSchema  *gojsonschema.Schema
+	querySchema *gojsonschema.Schema
 }
 
-func NewContractTemplate(code []byte, initSchema, execSchema, querySchema string) ContractTemplate {
-	_code := code
-	_initSchema := gojsonschema.NewStringLoader(initSchema)
-	_execSchema := gojsonschema.NewStringLoader(execSchema)
-	_querySchema := gojsonschema.NewStringLoader(querySchema)
+// NewContractTemplate creates a contract template from the given code and
+// JSON schemas of the messages. The schemas are compiled once, so that
+// validating a message does not parse them again.
+func NewContractTemplate(code []byte, initSchema, execSchema, querySchema string) (ContractTemplate, error) {
+	_initSchema, err := compileSchema(initSchema)
+	if err != nil {
+		return nil, fmt.Errorf("compiling init schema: %w", err)
+	}
+	_execSchema, err := compileSchema(execSchema)
+	if err != nil {
+		return nil, fmt.Errorf("compiling execute schema: %w", err)
+	}
+	_querySchema, err := compileSchema(querySchema)
+	if err != nil {
+		return nil, fmt.Errorf("compiling query schema: %w", err)
+	}
 
 	return &contractTemplate{
-		code:        _code,
+		code:        code,
 		initSchema:  _initSchema,
 		execSchema:  _execSchema,
 		querySchema: _querySchema,
-	}
+	}, nil
+}
+
+func compileSchema(schema string) (*gojsonschema.Schema, error) {
+	return gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
 }
 
 func (c *contractTemplate) Code() []byte {
@@ -80,9 +95,8 @@ func (c *contractTemplate) ValidateQueryMsg(msg []byte) error {
 }
 
 // validateMsg validates the given message against the given schema.
-func (*contractTemplate) validateMsg(msg []byte, schema gojsonschema.JSONLoader) error {
-	_msg := gojsonschema.NewBytesLoader(msg)
-	result, err := gojsonschema.Validate(schema, _msg)
+func (*contractTemplate) validateMsg(msg []byte, schema *gojsonschema.Schema) error {
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(msg))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,100 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package cosmwasm_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/perun-network/perun-cosmwasm-backend/channel/binding/schema"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/contract"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+func newTemplate(t testing.TB) client.ContractTemplate {
+	c, err := client.NewContractTemplate(contract.Code, contract.InitMsgSchema, contract.ExecuteMsgSchema, contract.QueryMsgSchema)
+	require.NoError(t, err)
+	return c
+}
+
+// disputeMsg returns a dispute message for a channel with the given number
+// of participants and assets.
+func disputeMsg(t testing.TB, numParts, numAssets int) []byte {
+	parts := make([]schema.OffIdentity, numParts)
+	sigs := make([]schema.Sig, numParts)
+	bals := make([]schema.NativeBalance, numParts)
+	for i := range parts {
+		parts[i] = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+		sigs[i] = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+		bals[i] = make(schema.NativeBalance, numAssets)
+		for j := range bals[i] {
+			bals[i][j] = schema.Coin{Amount: "1000", Denom: fmt.Sprintf("token%d", j)}
+		}
+	}
+	msg := schema.NewExecuteMsgDispute(schema.ExecuteMsgDispute{
+		Params: schema.Params{DisputeDuration: "60", Nonce: "AQID", Participants: parts},
+		Sigs:   sigs,
+		State:  schema.State{Balances: bals, ChannelID: "AQID", Version: "1"},
+	})
+	b, err := json.Marshal(msg)
+	require.NoError(t, err)
+	return b
+}
+
+func TestContractTemplate(t *testing.T) {
+	c := newTemplate(t)
+
+	assert.NoError(t, c.ValidateInitMsg([]byte(`{}`)))
+	assert.NoError(t, c.ValidateExecuteMsg(disputeMsg(t, 2, 1)))
+	assert.NoError(t, c.ValidateQueryMsg([]byte(`{"dispute": "AQID"}`)))
+
+	assert.Error(t, c.ValidateExecuteMsg([]byte(`{"dispute": "AQID"}`)))
+	assert.Error(t, c.ValidateQueryMsg([]byte(`{"unknown": "AQID"}`)))
+	assert.Error(t, c.ValidateQueryMsg([]byte(`{`)))
+
+	_, err := client.NewContractTemplate(contract.Code, `{"type": 1}`, contract.ExecuteMsgSchema, contract.QueryMsgSchema)
+	assert.Error(t, err)
+}
+
+// BenchmarkValidateExecuteMsg compares validating a dispute message against
+// the compiled schema with validating it against the schema source, which
+// parses the schema on every call.
+func BenchmarkValidateExecuteMsg(b *testing.B) {
+	c := newTemplate(b)
+	for _, n := range []int{2, 16} {
+		msg := disputeMsg(b, n, n/2)
+
+		b.Run(fmt.Sprintf("source/parts=%d", n), func(b *testing.B) {
+			loader := gojsonschema.NewStringLoader(contract.ExecuteMsgSchema)
+			for i := 0; i < b.N; i++ {
+				res, err := gojsonschema.Validate(loader, gojsonschema.NewBytesLoader(msg))
+				if err != nil || !res.Valid() {
+					b.Fatal("invalid message")
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("compiled/parts=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := c.ValidateExecuteMsg(msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package fault provides a client that injects faults into the calls of
+// another client, for testing how components behave when the chain
+// misbehaves.
+package fault
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Method identifies a client method into which faults can be injected.
+type Method string
+
+// Methods into which faults can be injected.
+const (
+	ExecuteContract    Method = "ExecuteContract"
+	SmartContractState Method = "SmartContractState"
+	GetLatestBlock     Method = "GetLatestBlock"
+)
+
+type (
+	// Scenario describes the faults that are injected into the calls of a
+	// client. The random decisions are derived from the seed.
+	Scenario struct {
+		Seed   int64             `json:"seed"`
+		Faults map[Method]Faults `json:"faults"`
+	}
+
+	// Faults describes the faults that are injected into the calls of a
+	// method. Each probability is in [0, 1] and is applied independently on
+	// every call.
+	Faults struct {
+		// Latency is the maximum latency added to a call. The latency of a call
+		// is drawn uniformly from [0, Latency].
+		Latency time.Duration `json:"latency"`
+		// Transient is the probability that a call fails with a transient
+		// gRPC error before it reaches the ledger.
+		Transient float64 `json:"transient"`
+		// Dropped is the probability that a transaction is applied on the
+		// ledger but its response is lost and an error is returned instead.
+		Dropped float64 `json:"dropped"`
+		// OutOfGas is the probability that a transaction fails with an
+		// out-of-gas error without being applied.
+		OutOfGas float64 `json:"out_of_gas"`
+		// Stale is the probability that a query is answered from a node that
+		// has not caught up yet, i.e., with the response of the previous
+		// call with the same request.
+		Stale float64 `json:"stale"`
+		// Reordered is the probability that a response is held back until
+		// the next call of the same method has returned or ReorderWindow has
+		// passed.
+		Reordered     float64       `json:"reordered"`
+		ReorderWindow time.Duration `json:"reorder_window"`
+	}
+)
+
+// Errors returned by the client for injected faults.
+var (
+	ErrTransient = status.Error(codes.Unavailable, "injected fault: node unavailable")
+	ErrDropped   = status.Error(codes.DeadlineExceeded, "injected fault: response dropped")
+	ErrOutOfGas  = sdkerrors.Wrap(sdkerrors.ErrOutOfGas, "injected fault")
+)
+
+// Client is a client that injects faults into the calls of the wrapped
+// client according to a scenario. Calls of methods that are not covered by
+// the scenario are passed through.
+type Client struct {
+	client.Client
+	scenario Scenario
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	last     map[Method]map[string]interface{} // The last responses for stale queries.
+	returned map[Method]chan struct{}          // Closed and replaced when a call returns.
+}
+
+var _ client.Client = &Client{}
+
+// NewClient creates a client that injects faults into the calls of the given
+// client according to the scenario.
+func NewClient(c client.Client, s Scenario) *Client {
+	return &Client{
+		Client:   c,
+		scenario: s,
+		rng:      rand.New(rand.NewSource(s.Seed)),
+		last:     make(map[Method]map[string]interface{}),
+		returned: make(map[Method]chan struct{}),
+	}
+}
+
+// ExecuteContract executes a function on a contract.
+func (c *Client) ExecuteContract(ctx context.Context, in *wtypes.MsgExecuteContract, opts ...grpc.CallOption) (*wtypes.MsgExecuteContractResponse, error) {
+	resp, err := c.call(ctx, ExecuteContract, "", func() (interface{}, error) {
+		return c.Client.ExecuteContract(ctx, in, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*wtypes.MsgExecuteContractResponse), nil
+}
+
+// SmartContractState performs a smart contract query.
+func (c *Client) SmartContractState(ctx context.Context, in *wtypes.QuerySmartContractStateRequest, opts ...grpc.CallOption) (*wtypes.QuerySmartContractStateResponse, error) {
+	key := in.Address + string(in.QueryData)
+	resp, err := c.call(ctx, SmartContractState, key, func() (interface{}, error) {
+		return c.Client.SmartContractState(ctx, in, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*wtypes.QuerySmartContractStateResponse), nil
+}
+
+// GetLatestBlock returns the latest block.
+func (c *Client) GetLatestBlock(ctx context.Context, in *tmservice.GetLatestBlockRequest, opts ...grpc.CallOption) (*tmservice.GetLatestBlockResponse, error) {
+	resp, err := c.call(ctx, GetLatestBlock, "", func() (interface{}, error) {
+		return c.Client.GetLatestBlock(ctx, in, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*tmservice.GetLatestBlockResponse), nil
+}
+
+// decision represents the faults drawn for a single call.
+type decision struct {
+	latency                                      time.Duration
+	transient, dropped, outOfGas, stale, reorder bool
+}
+
+// decide draws the faults for a call of the given method.
+func (c *Client) decide(m Method) decision {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f := c.scenario.Faults[m]
+	var d decision
+	if f.Latency > 0 {
+		d.latency = time.Duration(c.rng.Int63n(int64(f.Latency) + 1))
+	}
+	d.transient = c.rng.Float64() < f.Transient
+	d.dropped = c.rng.Float64() < f.Dropped
+	d.outOfGas = c.rng.Float64() < f.OutOfGas
+	d.stale = c.rng.Float64() < f.Stale
+	d.reorder = c.rng.Float64() < f.Reordered
+	return d
+}
+
+// call invokes fn and injects the faults drawn for the given method. The key
+// identifies the request for stale responses.
+func (c *Client) call(ctx context.Context, m Method, key string, fn func() (interface{}, error)) (interface{}, error) {
+	defer c.notifyReturned(m)
+	d := c.decide(m)
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(d.latency):
+	}
+
+	switch {
+	case d.transient:
+		return nil, ErrTransient
+	case d.outOfGas:
+		return nil, ErrOutOfGas
+	}
+
+	resp, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	if m != ExecuteContract {
+		resp = c.staleResponse(m, key, resp, d.stale)
+	}
+
+	if d.reorder {
+		c.awaitNextReturn(ctx, m)
+	}
+	if d.dropped {
+		return nil, ErrDropped
+	}
+	return resp, nil
+}
+
+// staleResponse returns the previous response for the given request if stale
+// is true and there is a previous response. Otherwise, it records and returns
+// the given response.
+func (c *Client) staleResponse(m Method, key string, resp interface{}, stale bool) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last, ok := c.last[m]
+	if !ok {
+		last = make(map[string]interface{})
+		c.last[m] = last
+	}
+	if prev, ok := last[key]; ok && stale {
+		return prev
+	}
+	last[key] = resp
+	return resp
+}
+
+// awaitNextReturn blocks until the next call of the given method returns,
+// the reorder window passes, or the context is done.
+func (c *Client) awaitNextReturn(ctx context.Context, m Method) {
+	c.mu.Lock()
+	returned := c.returnedChan(m)
+	window := c.scenario.Faults[m].ReorderWindow
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+	case <-returned:
+	case <-time.After(window):
+	}
+}
+
+// notifyReturned notifies the calls waiting for a call of the given method to
+// return.
+func (c *Client) notifyReturned(m Method) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	close(c.returnedChan(m))
+	c.returned[m] = make(chan struct{})
+}
+
+// returnedChan returns the channel that is closed when the next call of the
+// given method returns. The caller must hold the lock.
+func (c *Client) returnedChan(m Method) chan struct{} {
+	ch, ok := c.returned[m]
+	if !ok {
+		ch = make(chan struct{})
+		c.returned[m] = ch
+	}
+	return ch
+}
@@ -0,0 +1,102 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package fault_test
+
+import (
+	"context"
+	"testing"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/fault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// counterClient is a client that counts the executed transactions and returns
+// the count on queries.
+type counterClient struct {
+	client.Client
+	count int
+}
+
+func (c *counterClient) ExecuteContract(context.Context, *wtypes.MsgExecuteContract, ...grpc.CallOption) (*wtypes.MsgExecuteContractResponse, error) {
+	c.count++
+	return &wtypes.MsgExecuteContractResponse{}, nil
+}
+
+func (c *counterClient) SmartContractState(context.Context, *wtypes.QuerySmartContractStateRequest, ...grpc.CallOption) (*wtypes.QuerySmartContractStateResponse, error) {
+	return &wtypes.QuerySmartContractStateResponse{Data: []byte{byte(c.count)}}, nil
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	execute := func(c client.Client) error {
+		_, err := c.ExecuteContract(ctx, &wtypes.MsgExecuteContract{})
+		return err
+	}
+	query := func(c client.Client) []byte {
+		resp, err := c.SmartContractState(ctx, &wtypes.QuerySmartContractStateRequest{})
+		require.NoError(t, err, "query")
+		return resp.Data
+	}
+	newClient := func(f fault.Faults) (*counterClient, *fault.Client) {
+		cc := &counterClient{}
+		s := fault.Scenario{Faults: map[fault.Method]fault.Faults{
+			fault.ExecuteContract:    f,
+			fault.SmartContractState: f,
+		}}
+		return cc, fault.NewClient(cc, s)
+	}
+
+	t.Run("transient", func(t *testing.T) {
+		cc, fc := newClient(fault.Faults{Transient: 1})
+		assert.ErrorIs(t, execute(fc), fault.ErrTransient)
+		assert.Zero(t, cc.count, "not applied")
+	})
+
+	t.Run("out of gas", func(t *testing.T) {
+		cc, fc := newClient(fault.Faults{OutOfGas: 1})
+		assert.True(t, sdkerrors.ErrOutOfGas.Is(execute(fc)), "out of gas")
+		assert.Zero(t, cc.count, "not applied")
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		cc, fc := newClient(fault.Faults{Dropped: 1})
+		assert.ErrorIs(t, execute(fc), fault.ErrDropped)
+		assert.Equal(t, 1, cc.count, "applied")
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		cc, fc := newClient(fault.Faults{Stale: 1})
+		assert.Equal(t, []byte{0}, query(fc))
+		require.NoError(t, execute(fc), "execute")
+		assert.Equal(t, []byte{0}, query(fc), "stale")
+		assert.Equal(t, []byte{1}, query(cc), "fresh")
+	})
+
+	t.Run("seed", func(t *testing.T) {
+		s := fault.Scenario{Seed: 1, Faults: map[fault.Method]fault.Faults{
+			fault.ExecuteContract: {Transient: 0.5},
+		}}
+		c1 := fault.NewClient(&counterClient{}, s)
+		c2 := fault.NewClient(&counterClient{}, s)
+		for i := 0; i < 16; i++ {
+			assert.Equal(t, execute(c1), execute(c2), "same faults")
+		}
+	})
+}
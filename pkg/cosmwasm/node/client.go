@@ -1,6 +1,8 @@
 package node
 
 import (
+	"context"
+	"fmt"
 	"os"
 
 	"github.com/CosmWasm/wasmd/app"
@@ -9,19 +11,30 @@ import (
 	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
 	"github.com/cosmos/cosmos-sdk/crypto/keyring"
 	"github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/proof"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
 	"github.com/tendermint/tendermint/rpc/client/http"
 )
 
 // Client provides methods for interacting with a CosmWasm node.
+//
+// Messages are sent in signed transactions from the client's account. The
+// responses report the hash, height and gas used of the transaction in the
+// headers client.TxHashHeader, grpctypes.GRPCBlockHeightHeader and
+// client.GasUsedHeader.
 type Client struct {
-	wtypes.MsgClient
 	wtypes.QueryClient
 	tmservice.ServiceClient
+	clientCtx sdkclient.Context
 }
 
-var _ client.Client = &Client{}
+var (
+	_ client.Client = &Client{}
+	_ proof.Client  = &Client{}
+)
 
 // NewClient creates a new client.
 func NewClient(nodeURL string, chainID string, acc types.AccAddress, kr keyring.Keyring) (*Client, error) {
@@ -29,12 +42,19 @@ func NewClient(nodeURL string, chainID string, acc types.AccAddress, kr keyring.
 	if err != nil {
 		return nil, err
 	}
+	c := NewClientWithRPC(tendermintClient, chainID, acc, kr)
+	c.clientCtx.NodeURI = nodeURL
+	return c, nil
+}
 
+// NewClientWithRPC creates a new client that connects to the node with the
+// given Tendermint RPC client.
+func NewClientWithRPC(rpc rpcclient.Client, chainID string, acc types.AccAddress, kr keyring.Keyring) *Client {
 	encodingConfig := app.MakeEncodingConfig()
 
 	clientCtx := sdkclient.Context{
 		FromAddress:       acc,
-		Client:            tendermintClient,
+		Client:            rpc,
 		ChainID:           chainID,
 		JSONMarshaler:     encodingConfig.Marshaler,
 		InterfaceRegistry: encodingConfig.InterfaceRegistry,
@@ -56,12 +76,34 @@ func NewClient(nodeURL string, chainID string, acc types.AccAddress, kr keyring.
 		SkipConfirm:       true,
 		TxConfig:          encodingConfig.TxConfig,
 		AccountRetriever:  authtypes.AccountRetriever{},
-		NodeURI:           nodeURL,
 	}
 
 	return &Client{
-		MsgClient:     wtypes.NewMsgClient(clientCtx),
 		QueryClient:   wtypes.NewQueryClient(clientCtx),
 		ServiceClient: tmservice.NewServiceClient(clientCtx),
-	}, nil
+		clientCtx:     clientCtx,
+	}
+}
+
+// QueryStoreProof queries the value of the key in the store with the given
+// name in the state after the block at the given height, together with a
+// Merkle proof.
+func (c *Client) QueryStoreProof(ctx context.Context, storeName string, key []byte, height int64) (proof.StoreProof, error) {
+	node, err := c.clientCtx.GetNode()
+	if err != nil {
+		return proof.StoreProof{}, err
+	}
+
+	path := fmt.Sprintf("/store/%s/key", storeName)
+	opts := rpcclient.ABCIQueryOptions{Height: height, Prove: true}
+	res, err := node.ABCIQueryWithOptions(ctx, path, key, opts)
+	if err != nil {
+		return proof.StoreProof{}, err
+	}
+
+	r := res.Response
+	if !r.IsOK() {
+		return proof.StoreProof{}, sdkerrors.ABCIError(r.Codespace, r.Code, r.Log)
+	}
+	return proof.StoreProof{Value: r.Value, Proof: r.ProofOps, Height: r.Height}, nil
 }
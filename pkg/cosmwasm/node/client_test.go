@@ -0,0 +1,148 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package node_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/CosmWasm/wasmd/app"
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/types"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/node"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// testRPC is a Tendermint RPC client that answers the queries needed for
+// sending a transaction and includes broadcast transactions in a block with
+// the given result.
+type testRPC struct {
+	rpcclient.Client
+	acc       types.AccAddress
+	height    int64
+	deliverTx abci.ResponseDeliverTx
+	raw       []tmtypes.Tx
+	txs       []types.Tx
+}
+
+func (r *testRPC) ABCIQueryWithOptions(_ context.Context, path string, _ tmbytes.HexBytes, _ rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	var (
+		value []byte
+		err   error
+	)
+	switch path {
+	case "/cosmos.auth.v1beta1.Query/Account":
+		var acc *codectypes.Any
+		if acc, err = codectypes.NewAnyWithValue(authtypes.NewBaseAccount(r.acc, nil, 1, 0)); err != nil {
+			return nil, err
+		}
+		value, err = (&authtypes.QueryAccountResponse{Account: acc}).Marshal()
+	case "/cosmos.tx.v1beta1.Service/Simulate":
+		value, err = (&txtypes.SimulateResponse{GasInfo: &types.GasInfo{GasUsed: 100000}}).Marshal()
+	default:
+		return nil, fmt.Errorf("unexpected query: %s", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultABCIQuery{Response: abci.ResponseQuery{Value: value, Height: r.height}}, nil
+}
+
+func (r *testRPC) BroadcastTxCommit(_ context.Context, tx tmtypes.Tx) (*ctypes.ResultBroadcastTxCommit, error) {
+	decoded, err := app.MakeEncodingConfig().TxConfig.TxDecoder()(tx)
+	if err != nil {
+		return nil, err
+	}
+	r.raw = append(r.raw, tx)
+	r.txs = append(r.txs, decoded)
+	return &ctypes.ResultBroadcastTxCommit{
+		DeliverTx: r.deliverTx,
+		Hash:      tx.Hash(),
+		Height:    r.height + 1,
+	}, nil
+}
+
+func TestClient_Broadcast(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	kr := keyring.NewInMemory()
+	info, _, err := kr.NewMnemonic("test", keyring.English, types.FullFundraiserPath, hd.Secp256k1)
+	require.NoError(t, err, "creating key")
+	acc := info.GetAddress()
+
+	msg := &wtypes.MsgExecuteContract{
+		Sender:   acc.String(),
+		Contract: acc.String(),
+		Msg:      []byte(`{}`),
+	}
+
+	t.Run("success", func(t *testing.T) {
+		resp := wtypes.MsgExecuteContractResponse{Data: []byte("result")}
+		respData, err := resp.Marshal()
+		require.NoError(t, err)
+		txData, err := (&types.TxMsgData{Data: []*types.MsgData{{MsgType: msg.Type(), Data: respData}}}).Marshal()
+		require.NoError(t, err)
+
+		rpc := &testRPC{acc: acc, height: 10, deliverTx: abci.ResponseDeliverTx{Data: txData, GasUsed: 1234}}
+		c := node.NewClientWithRPC(rpc, "test-chain", acc, kr)
+
+		var md metadata.MD
+		res, err := c.ExecuteContract(ctx, msg, grpc.Header(&md))
+		require.NoError(t, err, "executing contract")
+		assert.Equal(t, resp.Data, res.Data, "response")
+
+		require.Len(t, rpc.txs, 1, "broadcast transactions")
+		assert.Equal(t, []types.Msg{msg}, rpc.txs[0].GetMsgs(), "messages")
+		assert.Equal(t, []string{fmt.Sprintf("%X", rpc.raw[0].Hash())}, md.Get(client.TxHashHeader), "tx hash")
+		assert.Equal(t, []string{"11"}, md.Get(grpctypes.GRPCBlockHeightHeader), "height")
+		assert.Equal(t, []string{"1234"}, md.Get(client.GasUsedHeader), "gas used")
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		rpc := &testRPC{acc: acc, height: 10, deliverTx: abci.ResponseDeliverTx{
+			Code:      5,
+			Codespace: "wasm",
+			Log:       "Unknown dispute: execute wasm contract failed",
+			GasUsed:   4321,
+		}}
+		c := node.NewClientWithRPC(rpc, "test-chain", acc, kr)
+
+		var md metadata.MD
+		_, err := c.ExecuteContract(ctx, msg, grpc.Header(&md))
+		require.Error(t, err, "executing contract")
+		assert.Contains(t, err.Error(), "Unknown dispute", "error")
+		assert.Len(t, md.Get(client.TxHashHeader), 1, "tx hash")
+		assert.Equal(t, []string{"11"}, md.Get(grpctypes.GRPCBlockHeightHeader), "height")
+		assert.Equal(t, []string{"4321"}, md.Get(client.GasUsedHeader), "gas used")
+	})
+}
@@ -0,0 +1,532 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultMaxLag is the default maximum time by which the latest block of
+	// a node may lag behind the current time and the latest block of the most
+	// up-to-date node.
+	DefaultMaxLag = 30 * time.Second
+	// DefaultCheckInterval is the default interval at which the health of the
+	// nodes is checked.
+	DefaultCheckInterval = 10 * time.Second
+)
+
+// ErrNoHealthyNode is returned if none of the nodes of a FailoverClient is
+// healthy. It is a gRPC Unavailable error so that callers treat it as
+// transient.
+var ErrNoHealthyNode = status.Error(codes.Unavailable, "no healthy node")
+
+// FailoverClient is a client that routes calls to several nodes of the same
+// chain.
+//
+// The nodes are health-checked via GetSyncing and GetLatestBlock. A node is
+// healthy if it responds, is not syncing, and its latest block time lags
+// neither behind the current time nor behind the latest block time of the
+// most up-to-date node by more than the maximum lag. Calls are routed to the healthy node with the highest
+// block, preferring nodes with lower latency. If a node fails, it is
+// considered unhealthy until the next health check and the call is repeated
+// on the next node. Queries fail over on errors caused by the node, e.g.,
+// transport errors, but not on errors returned by the application, e.g., a
+// contract. Transactions only fail over if the node was not reachable, so
+// that a transaction is never sent twice.
+type FailoverClient struct {
+	nodes         []*failoverNode
+	maxLag        time.Duration
+	checkInterval time.Duration
+	log           log.Logger
+
+	checkMu sync.Mutex // Serializes health checks.
+	mu      sync.Mutex
+	checked time.Time
+}
+
+var _ client.Client = &FailoverClient{}
+
+// failoverNode represents a node of a FailoverClient. Its health is guarded by
+// the client's mutex.
+type failoverNode struct {
+	idx     int
+	client  client.Client
+	err     error // The reason why the node is unhealthy, nil if healthy.
+	height  int64
+	time    time.Time
+	latency time.Duration
+}
+
+// FailoverOpt is an optional parameter for NewFailoverClient.
+type FailoverOpt func(*FailoverClient)
+
+// FailoverMaxLagOpt sets the maximum time by which the latest block of a node
+// may lag behind the current time and the latest block of the most up-to-date
+// node.
+func FailoverMaxLagOpt(d time.Duration) FailoverOpt {
+	return func(c *FailoverClient) {
+		c.maxLag = d
+	}
+}
+
+// FailoverCheckIntervalOpt sets the interval at which the health of the nodes
+// is checked.
+func FailoverCheckIntervalOpt(d time.Duration) FailoverOpt {
+	return func(c *FailoverClient) {
+		c.checkInterval = d
+	}
+}
+
+// FailoverLoggerOpt sets the logger. By default, go-perun's framework logger
+// is used.
+func FailoverLoggerOpt(l log.Logger) FailoverOpt {
+	return func(c *FailoverClient) {
+		c.log = l
+	}
+}
+
+// NewFailoverClient creates a new client that routes calls to the given
+// clients, which must be connected to nodes of the same chain.
+func NewFailoverClient(clients []client.Client, opts ...FailoverOpt) *FailoverClient {
+	c := &FailoverClient{
+		nodes:         make([]*failoverNode, len(clients)),
+		maxLag:        DefaultMaxLag,
+		checkInterval: DefaultCheckInterval,
+		log:           log.Default(),
+	}
+	for i, cl := range clients {
+		c.nodes[i] = &failoverNode{idx: i, client: cl, err: errors.New("not checked")}
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewFailoverNodeClient creates a new client that routes calls to the nodes
+// at the given URLs.
+func NewFailoverNodeClient(nodeURLs []string, chainID string, acc types.AccAddress, kr keyring.Keyring, opts ...FailoverOpt) (*FailoverClient, error) {
+	clients := make([]client.Client, len(nodeURLs))
+	for i, url := range nodeURLs {
+		c, err := NewClient(url, chainID, acc, kr)
+		if err != nil {
+			return nil, fmt.Errorf("creating client for %s: %w", url, err)
+		}
+		clients[i] = c
+	}
+	return NewFailoverClient(clients, opts...), nil
+}
+
+// Check checks the health of all nodes. It returns ErrNoHealthyNode if none
+// of them is healthy.
+func (c *FailoverClient) Check(ctx context.Context) error {
+	c.checkMu.Lock()
+	defer c.checkMu.Unlock()
+
+	type result struct {
+		err     error
+		height  int64
+		time    time.Time
+		latency time.Duration
+	}
+	results := make([]result, len(c.nodes))
+	var wg sync.WaitGroup
+	wg.Add(len(c.nodes))
+	for i, n := range c.nodes {
+		go func(i int, n client.Client) {
+			defer wg.Done()
+			r := &results[i]
+			start := time.Now()
+			r.height, r.time, r.err = checkNode(ctx, n)
+			r.latency = time.Since(start)
+		}(i, n.client)
+	}
+	wg.Wait()
+
+	var latest time.Time
+	for _, r := range results {
+		if r.err == nil && r.time.After(latest) {
+			latest = r.time
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for i, r := range results {
+		n := c.nodes[i]
+		healthy := n.err == nil
+		n.err, n.height, n.time, n.latency = r.err, r.height, r.time, r.latency
+		switch {
+		case n.err != nil:
+		case latest.Sub(n.time) > c.maxLag:
+			n.err = fmt.Errorf("latest block time %v lags behind %v", n.time, latest)
+		case now.Sub(n.time) > c.maxLag:
+			// All nodes may be stale, e.g., if the chain halted.
+			n.err = fmt.Errorf("latest block time %v lags behind current time %v", n.time, now)
+		}
+		if healthy && n.err != nil {
+			c.nodeLogger(n).WithError(n.err).Warnf("Node unhealthy")
+		}
+	}
+	c.checked = time.Now()
+	return c.noHealthyNodeError()
+}
+
+// checkNode returns the height and time of the latest block of the given
+// node, or an error if the node is not available or syncing.
+func checkNode(ctx context.Context, n client.Client) (int64, time.Time, error) {
+	syncing, err := n.GetSyncing(ctx, &tmservice.GetSyncingRequest{})
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("querying syncing: %w", err)
+	} else if syncing.Syncing {
+		return 0, time.Time{}, errors.New("node is syncing")
+	}
+
+	resp, err := n.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("querying latest block: %w", err)
+	}
+	h := resp.Block.Header
+	return h.Height, h.Time, nil
+}
+
+// healthyNodes returns the healthy nodes in the order in which they should be
+// called. The health is checked if the check interval has passed or if no
+// node is healthy.
+func (c *FailoverClient) healthyNodes(ctx context.Context) ([]*failoverNode, error) {
+	c.mu.Lock()
+	nodes := c.healthyNodesLocked()
+	check := len(nodes) == 0 || time.Since(c.checked) >= c.checkInterval
+	c.mu.Unlock()
+
+	if check {
+		if err := c.Check(ctx); err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		nodes = c.healthyNodesLocked()
+		c.mu.Unlock()
+	}
+	return nodes, nil
+}
+
+// healthyNodesLocked returns the healthy nodes, sorted by descending height
+// and ascending latency. The caller must hold the lock.
+func (c *FailoverClient) healthyNodesLocked() []*failoverNode {
+	var nodes []*failoverNode
+	for _, n := range c.nodes {
+		if n.err == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].height != nodes[j].height {
+			return nodes[i].height > nodes[j].height
+		}
+		return nodes[i].latency < nodes[j].latency
+	})
+	return nodes
+}
+
+// noHealthyNodeError returns ErrNoHealthyNode, annotated with the reason why
+// the first node is unhealthy, if no node is healthy. The caller must hold
+// the lock.
+func (c *FailoverClient) noHealthyNodeError() error {
+	for _, n := range c.nodes {
+		if n.err == nil {
+			return nil
+		}
+	}
+	if len(c.nodes) == 0 {
+		return ErrNoHealthyNode
+	}
+	return fmt.Errorf("%w: %v", ErrNoHealthyNode, c.nodes[0].err)
+}
+
+// setUnhealthy marks the given node as unhealthy until the next check.
+func (c *FailoverClient) setUnhealthy(n *failoverNode, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n.err == nil {
+		c.nodeLogger(n).WithError(err).Warnf("Node failed, failing over")
+	}
+	n.err = err
+}
+
+// nodeLogger returns the logger with the node field set.
+func (c *FailoverClient) nodeLogger(n *failoverNode) log.Logger {
+	return c.log.WithFields(log.Fields{log.NodeKey: n.idx, log.HeightKey: n.height})
+}
+
+// call calls fn on the healthy nodes until it succeeds or returns an error
+// for which failover returns false.
+func (c *FailoverClient) call(ctx context.Context, failover func(error) bool, fn func(client.Client) error) error {
+	nodes, err := c.healthyNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range nodes {
+		err = fn(n.client)
+		if err == nil || !failover(err) || ctx.Err() != nil {
+			return err
+		}
+		c.setUnhealthy(n, err)
+	}
+	return err
+}
+
+// query performs a query with failover on node errors.
+func (c *FailoverClient) query(ctx context.Context, fn func(client.Client) error) error {
+	return c.call(ctx, isNodeError, fn)
+}
+
+// send sends a transaction with failover if the node was not reachable.
+func (c *FailoverClient) send(ctx context.Context, fn func(client.Client) error) error {
+	return c.call(ctx, isUnreachableError, fn)
+}
+
+// isNodeError returns whether err was caused by the node rather than by the
+// application, e.g., a contract. Application errors carry an ABCI codespace
+// or, if returned via gRPC, the code Unknown or a code describing the
+// request. Errors of nodes are returned as gRPC status errors without a
+// codespace, so gRPC errors are classified by their code.
+func isNodeError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if codespace, _, _ := sdkerrors.ABCIInfo(err, false); codespace != sdkerrors.UndefinedCodespace {
+		return false
+	}
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return true
+	}
+	switch grpcErr.GRPCStatus().Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Aborted, codes.Internal, codes.Unimplemented, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// isUnreachableError returns whether err indicates that a request did not
+// reach the node.
+func isUnreachableError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	return errors.As(err, &grpcErr) && grpcErr.GRPCStatus().Code() == codes.Unavailable
+}
+
+// StoreCode stores a contract code.
+func (c *FailoverClient) StoreCode(ctx context.Context, in *wtypes.MsgStoreCode, opts ...grpc.CallOption) (resp *wtypes.MsgStoreCodeResponse, err error) {
+	err = c.send(ctx, func(n client.Client) (err error) {
+		resp, err = n.StoreCode(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// InstantiateContract instantiates a contract.
+func (c *FailoverClient) InstantiateContract(ctx context.Context, in *wtypes.MsgInstantiateContract, opts ...grpc.CallOption) (resp *wtypes.MsgInstantiateContractResponse, err error) {
+	err = c.send(ctx, func(n client.Client) (err error) {
+		resp, err = n.InstantiateContract(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// ExecuteContract executes a function on a contract.
+func (c *FailoverClient) ExecuteContract(ctx context.Context, in *wtypes.MsgExecuteContract, opts ...grpc.CallOption) (resp *wtypes.MsgExecuteContractResponse, err error) {
+	err = c.send(ctx, func(n client.Client) (err error) {
+		resp, err = n.ExecuteContract(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// MigrateContract migrates a contract.
+func (c *FailoverClient) MigrateContract(ctx context.Context, in *wtypes.MsgMigrateContract, opts ...grpc.CallOption) (resp *wtypes.MsgMigrateContractResponse, err error) {
+	err = c.send(ctx, func(n client.Client) (err error) {
+		resp, err = n.MigrateContract(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// UpdateAdmin sets a new admin for a contract.
+func (c *FailoverClient) UpdateAdmin(ctx context.Context, in *wtypes.MsgUpdateAdmin, opts ...grpc.CallOption) (resp *wtypes.MsgUpdateAdminResponse, err error) {
+	err = c.send(ctx, func(n client.Client) (err error) {
+		resp, err = n.UpdateAdmin(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// ClearAdmin removes the admin of a contract.
+func (c *FailoverClient) ClearAdmin(ctx context.Context, in *wtypes.MsgClearAdmin, opts ...grpc.CallOption) (resp *wtypes.MsgClearAdminResponse, err error) {
+	err = c.send(ctx, func(n client.Client) (err error) {
+		resp, err = n.ClearAdmin(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// ContractInfo queries the metadata of a contract.
+func (c *FailoverClient) ContractInfo(ctx context.Context, in *wtypes.QueryContractInfoRequest, opts ...grpc.CallOption) (resp *wtypes.QueryContractInfoResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.ContractInfo(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// ContractHistory queries the code history of a contract.
+func (c *FailoverClient) ContractHistory(ctx context.Context, in *wtypes.QueryContractHistoryRequest, opts ...grpc.CallOption) (resp *wtypes.QueryContractHistoryResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.ContractHistory(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// ContractsByCode lists the contracts instantiated from a code.
+func (c *FailoverClient) ContractsByCode(ctx context.Context, in *wtypes.QueryContractsByCodeRequest, opts ...grpc.CallOption) (resp *wtypes.QueryContractsByCodeResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.ContractsByCode(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// AllContractState queries all raw state of a contract.
+func (c *FailoverClient) AllContractState(ctx context.Context, in *wtypes.QueryAllContractStateRequest, opts ...grpc.CallOption) (resp *wtypes.QueryAllContractStateResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.AllContractState(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// RawContractState queries raw state of a contract.
+func (c *FailoverClient) RawContractState(ctx context.Context, in *wtypes.QueryRawContractStateRequest, opts ...grpc.CallOption) (resp *wtypes.QueryRawContractStateResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.RawContractState(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// SmartContractState performs a smart contract query.
+func (c *FailoverClient) SmartContractState(ctx context.Context, in *wtypes.QuerySmartContractStateRequest, opts ...grpc.CallOption) (resp *wtypes.QuerySmartContractStateResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.SmartContractState(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// Code queries a contract code.
+func (c *FailoverClient) Code(ctx context.Context, in *wtypes.QueryCodeRequest, opts ...grpc.CallOption) (resp *wtypes.QueryCodeResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.Code(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// Codes lists the contract codes.
+func (c *FailoverClient) Codes(ctx context.Context, in *wtypes.QueryCodesRequest, opts ...grpc.CallOption) (resp *wtypes.QueryCodesResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.Codes(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// GetNodeInfo queries the node info of the preferred node.
+func (c *FailoverClient) GetNodeInfo(ctx context.Context, in *tmservice.GetNodeInfoRequest, opts ...grpc.CallOption) (resp *tmservice.GetNodeInfoResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.GetNodeInfo(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// GetSyncing queries whether the preferred node is syncing.
+func (c *FailoverClient) GetSyncing(ctx context.Context, in *tmservice.GetSyncingRequest, opts ...grpc.CallOption) (resp *tmservice.GetSyncingResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.GetSyncing(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// GetLatestBlock returns the latest block.
+func (c *FailoverClient) GetLatestBlock(ctx context.Context, in *tmservice.GetLatestBlockRequest, opts ...grpc.CallOption) (resp *tmservice.GetLatestBlockResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.GetLatestBlock(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// GetBlockByHeight queries the block at a given height.
+func (c *FailoverClient) GetBlockByHeight(ctx context.Context, in *tmservice.GetBlockByHeightRequest, opts ...grpc.CallOption) (resp *tmservice.GetBlockByHeightResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.GetBlockByHeight(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// GetLatestValidatorSet queries the latest validator set.
+func (c *FailoverClient) GetLatestValidatorSet(ctx context.Context, in *tmservice.GetLatestValidatorSetRequest, opts ...grpc.CallOption) (resp *tmservice.GetLatestValidatorSetResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.GetLatestValidatorSet(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// GetValidatorSetByHeight queries the validator set at a given height.
+func (c *FailoverClient) GetValidatorSetByHeight(ctx context.Context, in *tmservice.GetValidatorSetByHeightRequest, opts ...grpc.CallOption) (resp *tmservice.GetValidatorSetByHeightResponse, err error) {
+	err = c.query(ctx, func(n client.Client) (err error) {
+		resp, err = n.GetValidatorSetByHeight(ctx, in, opts...)
+		return
+	})
+	return
+}
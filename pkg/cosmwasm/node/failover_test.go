@@ -0,0 +1,127 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package node_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/node"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/simulation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testNode is a simulated node whose contract calls return err.
+type testNode struct {
+	*simulation.Client
+	err   error
+	calls int
+}
+
+func (n *testNode) ExecuteContract(context.Context, *wtypes.MsgExecuteContract, ...grpc.CallOption) (*wtypes.MsgExecuteContractResponse, error) {
+	n.calls++
+	if n.err != nil {
+		return nil, n.err
+	}
+	return &wtypes.MsgExecuteContractResponse{}, nil
+}
+
+func (n *testNode) SmartContractState(context.Context, *wtypes.QuerySmartContractStateRequest, ...grpc.CallOption) (*wtypes.QuerySmartContractStateResponse, error) {
+	n.calls++
+	if n.err != nil {
+		return nil, n.err
+	}
+	return &wtypes.QuerySmartContractStateResponse{}, nil
+}
+
+func TestFailoverClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const maxLag = time.Minute
+	// newClient returns a failover client for two nodes. The first node is
+	// preferred, as it is one block ahead. The latest block of the second
+	// node lags behind by the given duration.
+	newClient := func(err error, lag time.Duration) (*testNode, *testNode, *node.FailoverClient) {
+		a := &testNode{Client: simulation.NewTestClient(t), err: err}
+		b := &testNode{Client: simulation.NewTestClient(t)}
+		now := time.Now()
+		a.SetBlockTime(now)
+		b.SetBlockTime(now.Add(-lag))
+		a.SetBlockTime(now.Add(time.Second))
+		return a, b, node.NewFailoverClient([]client.Client{a, b}, node.FailoverMaxLagOpt(maxLag))
+	}
+	query := func(c client.Client) error {
+		_, err := c.SmartContractState(ctx, &wtypes.QuerySmartContractStateRequest{})
+		return err
+	}
+
+	t.Run("failover", func(t *testing.T) {
+		a, b, c := newClient(status.Error(codes.Unavailable, ""), 0)
+		require.NoError(t, query(c))
+		assert.Equal(t, 1, a.calls)
+		assert.Equal(t, 1, b.calls)
+
+		require.NoError(t, query(c))
+		assert.Equal(t, 1, a.calls, "unhealthy node skipped")
+		assert.Equal(t, 2, b.calls)
+	})
+
+	t.Run("application error", func(t *testing.T) {
+		a, b, c := newClient(wtypes.ErrQueryFailed, 0)
+		assert.ErrorIs(t, query(c), wtypes.ErrQueryFailed)
+		assert.Equal(t, 1, a.calls)
+		assert.Zero(t, b.calls)
+	})
+
+	t.Run("contract error", func(t *testing.T) {
+		a, b, c := newClient(status.Error(codes.Unknown, "Unknown dispute: query wasm contract failed"), 0)
+		assert.Equal(t, codes.Unknown, status.Code(query(c)))
+		assert.Equal(t, 1, a.calls)
+		assert.Zero(t, b.calls, "healthy node not failed over")
+
+		require.Error(t, query(c))
+		assert.Equal(t, 2, a.calls, "node still healthy")
+	})
+
+	t.Run("uncertain transaction", func(t *testing.T) {
+		a, b, c := newClient(status.Error(codes.DeadlineExceeded, ""), 0)
+		_, err := c.ExecuteContract(ctx, &wtypes.MsgExecuteContract{})
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+		assert.Equal(t, 1, a.calls)
+		assert.Zero(t, b.calls, "not sent twice")
+	})
+
+	t.Run("lagging node", func(t *testing.T) {
+		a, b, c := newClient(status.Error(codes.Unavailable, ""), 2*maxLag)
+		assert.Equal(t, codes.Unavailable, status.Code(query(c)))
+		assert.Equal(t, 1, a.calls)
+		assert.Zero(t, b.calls, "lagging node refused")
+	})
+	t.Run("stale nodes", func(t *testing.T) {
+		a := &testNode{Client: simulation.NewTestClient(t)}
+		a.SetBlockTime(time.Now().Add(-2 * maxLag))
+		c := node.NewFailoverClient([]client.Client{a}, node.FailoverMaxLagOpt(maxLag))
+		assert.ErrorIs(t, query(c), node.ErrNoHealthyNode)
+		assert.Zero(t, a.calls, "stale node refused")
+	})
+}
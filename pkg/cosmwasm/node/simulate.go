@@ -0,0 +1,64 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package node
+
+import (
+	"context"
+	"fmt"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	"github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+)
+
+var _ client.Simulator = &Client{}
+
+// SimulateExecuteContracts simulates executing the messages in the given
+// order, each on the state resulting from the previous ones, and returns the
+// gas used by each of them.
+//
+// The node simulates a transaction containing the first i messages for each
+// i, and the gas used by a message is the difference between two subsequent
+// simulations. Hence, the fixed costs of a transaction, like signature
+// verification, are only included in the gas used by the first message.
+func (c *Client) SimulateExecuteContracts(ctx context.Context, msgs []*wtypes.MsgExecuteContract) ([]uint64, error) {
+	clientCtx := c.clientCtx
+	txf, err := tx.PrepareFactory(clientCtx, tx.Factory{}.
+		WithTxConfig(clientCtx.TxConfig).
+		WithAccountRetriever(clientCtx.AccountRetriever).
+		WithChainID(clientCtx.ChainID).
+		WithSignMode(signing.SignMode_SIGN_MODE_DIRECT))
+	if err != nil {
+		return nil, fmt.Errorf("preparing transaction: %w", err)
+	}
+
+	sdkMsgs := make([]types.Msg, len(msgs))
+	gas := make([]uint64, len(msgs))
+	var prev uint64
+	for i, msg := range msgs {
+		sdkMsgs[i] = msg
+		res, _, err := tx.CalculateGas(clientCtx.QueryWithData, txf, sdkMsgs[:i+1]...)
+		if err != nil {
+			return nil, fmt.Errorf("simulating message %d: %w", i, err)
+		}
+		if used := res.GasInfo.GasUsed; used > prev {
+			gas[i] = used - prev
+			prev = used
+		}
+	}
+	return gas, nil
+}
@@ -0,0 +1,183 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	"github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// msgResponse is the response of a message, which is a protobuf message.
+type msgResponse interface {
+	Unmarshal([]byte) error
+}
+
+// gasAdjustment is the factor by which the simulated gas of a transaction is
+// multiplied to obtain its gas limit.
+const gasAdjustment = 1.3
+
+// StoreCode stores a contract code.
+func (c *Client) StoreCode(ctx context.Context, in *wtypes.MsgStoreCode, opts ...grpc.CallOption) (*wtypes.MsgStoreCodeResponse, error) {
+	var resp wtypes.MsgStoreCodeResponse
+	if err := c.broadcast(ctx, in, &resp, opts); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// InstantiateContract instantiates a contract.
+func (c *Client) InstantiateContract(ctx context.Context, in *wtypes.MsgInstantiateContract, opts ...grpc.CallOption) (*wtypes.MsgInstantiateContractResponse, error) {
+	var resp wtypes.MsgInstantiateContractResponse
+	if err := c.broadcast(ctx, in, &resp, opts); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ExecuteContract executes a function on a contract.
+func (c *Client) ExecuteContract(ctx context.Context, in *wtypes.MsgExecuteContract, opts ...grpc.CallOption) (*wtypes.MsgExecuteContractResponse, error) {
+	var resp wtypes.MsgExecuteContractResponse
+	if err := c.broadcast(ctx, in, &resp, opts); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// MigrateContract migrates a contract.
+func (c *Client) MigrateContract(ctx context.Context, in *wtypes.MsgMigrateContract, opts ...grpc.CallOption) (*wtypes.MsgMigrateContractResponse, error) {
+	var resp wtypes.MsgMigrateContractResponse
+	if err := c.broadcast(ctx, in, &resp, opts); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateAdmin sets a new admin for a contract.
+func (c *Client) UpdateAdmin(ctx context.Context, in *wtypes.MsgUpdateAdmin, opts ...grpc.CallOption) (*wtypes.MsgUpdateAdminResponse, error) {
+	var resp wtypes.MsgUpdateAdminResponse
+	if err := c.broadcast(ctx, in, &resp, opts); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ClearAdmin removes the admin of a contract.
+func (c *Client) ClearAdmin(ctx context.Context, in *wtypes.MsgClearAdmin, opts ...grpc.CallOption) (*wtypes.MsgClearAdminResponse, error) {
+	var resp wtypes.MsgClearAdminResponse
+	if err := c.broadcast(ctx, in, &resp, opts); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// broadcast sends the message in a transaction signed by the client's account
+// and waits until it is included in a block. The response of the message is
+// decoded into resp. The hash, height and gas used of the transaction are
+// reported in the headers of the call options, also if the transaction
+// failed.
+func (c *Client) broadcast(ctx context.Context, msg types.Msg, resp msgResponse, opts []grpc.CallOption) error {
+	clientCtx := c.clientCtx
+	info, err := clientCtx.Keyring.KeyByAddress(clientCtx.FromAddress)
+	if err != nil {
+		return fmt.Errorf("looking up key: %w", err)
+	}
+	txf, err := tx.PrepareFactory(clientCtx, tx.Factory{}.
+		WithTxConfig(clientCtx.TxConfig).
+		WithAccountRetriever(clientCtx.AccountRetriever).
+		WithKeybase(clientCtx.Keyring).
+		WithChainID(clientCtx.ChainID).
+		WithGasAdjustment(gasAdjustment).
+		WithSignMode(signing.SignMode_SIGN_MODE_DIRECT))
+	if err != nil {
+		return fmt.Errorf("preparing transaction: %w", err)
+	}
+	_, gas, err := tx.CalculateGas(clientCtx.QueryWithData, txf, msg)
+	if err != nil {
+		return fmt.Errorf("simulating transaction: %w", err)
+	}
+
+	txb, err := tx.BuildUnsignedTx(txf.WithGas(gas), msg)
+	if err != nil {
+		return fmt.Errorf("building transaction: %w", err)
+	}
+	if err := tx.Sign(txf, info.GetName(), txb, true); err != nil {
+		return fmt.Errorf("signing transaction: %w", err)
+	}
+	txBytes, err := clientCtx.TxConfig.TxEncoder()(txb.GetTx())
+	if err != nil {
+		return fmt.Errorf("encoding transaction: %w", err)
+	}
+
+	res, err := clientCtx.BroadcastTx(txBytes)
+	if err != nil {
+		return err
+	}
+	setHeader(opts, txHeader(res))
+	if res.Code != 0 {
+		return sdkerrors.ABCIError(res.Codespace, res.Code, res.RawLog)
+	}
+	return decodeMsgResponse(res, resp)
+}
+
+// txHeader returns the header reporting the hash, height and gas used of the
+// transaction.
+func txHeader(res *types.TxResponse) metadata.MD {
+	return metadata.Pairs(
+		client.TxHashHeader, res.TxHash,
+		grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(res.Height, 10),
+		client.GasUsedHeader, strconv.FormatInt(res.GasUsed, 10),
+	)
+}
+
+// decodeMsgResponse decodes the response of the single message of the
+// transaction into resp.
+func decodeMsgResponse(res *types.TxResponse, resp msgResponse) error {
+	data, err := hex.DecodeString(res.Data)
+	if err != nil {
+		return fmt.Errorf("decoding transaction data: %w", err)
+	}
+	var msgData types.TxMsgData
+	if err := msgData.Unmarshal(data); err != nil {
+		return fmt.Errorf("decoding transaction data: %w", err)
+	}
+	if len(msgData.Data) != 1 {
+		return fmt.Errorf("expected 1 message response, got %d", len(msgData.Data))
+	}
+	if err := resp.Unmarshal(msgData.Data[0].Data); err != nil {
+		return fmt.Errorf("decoding message response: %w", err)
+	}
+	return nil
+}
+
+// setHeader sets the header of all header call options to md.
+func setHeader(opts []grpc.CallOption, md metadata.MD) {
+	for _, opt := range opts {
+		if h, ok := opt.(grpc.HeaderCallOption); ok {
+			*h.HeaderAddr = md
+		}
+	}
+}
@@ -0,0 +1,187 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package proof provides contract state queries that are verified with ICS23
+// Merkle proofs against trusted block headers, so that they do not rely on
+// the honesty of the queried node.
+package proof
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/store/rootmulti"
+	"github.com/cosmos/cosmos-sdk/types"
+	"github.com/tendermint/tendermint/crypto/merkle"
+	"github.com/tendermint/tendermint/light"
+	tmcrypto "github.com/tendermint/tendermint/proto/tendermint/crypto"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+var (
+	// ErrInvalidProof is returned if a query result could not be verified.
+	ErrInvalidProof = errors.New("invalid proof")
+	// ErrNotCommitted is returned by a Client if the state at the queried
+	// height has not been committed yet. The query can be retried after the
+	// next block.
+	ErrNotCommitted = errors.New("state not committed")
+)
+
+// StoreProof is the value of a key in a store together with a Merkle proof
+// against the app hash of the state at the given height.
+type StoreProof struct {
+	Value  []byte // Empty if the key is not set.
+	Proof  *tmcrypto.ProofOps
+	Height int64
+}
+
+// Client provides store queries with Merkle proofs.
+type Client interface {
+	// QueryStoreProof queries the value of the key in the store with the given
+	// name in the state after the block at the given height.
+	QueryStoreProof(ctx context.Context, storeName string, key []byte, height int64) (StoreProof, error)
+}
+
+// Enabler is implemented by clients that only provide proofs after they
+// have been enabled, like the simulated client.
+type Enabler interface {
+	// EnableStoreProofs makes the client provide proofs for the state after
+	// the current block.
+	EnableStoreProofs()
+}
+
+// HeaderVerifier provides verified block headers.
+type HeaderVerifier interface {
+	// VerifiedHeader returns the verified header at the given height. If the
+	// height is 0, the latest verified header is returned.
+	VerifiedHeader(ctx context.Context, height int64) (tmproto.Header, error)
+}
+
+// Querier performs contract state queries that are verified against the
+// headers of a HeaderVerifier.
+type Querier struct {
+	client  Client
+	headers HeaderVerifier
+	runtime *merkle.ProofRuntime
+}
+
+// NewQuerier creates a new querier that queries proofs from the given client
+// and verifies them against the headers of the given verifier. If the client
+// is a Enabler, its proofs are enabled.
+func NewQuerier(c Client, h HeaderVerifier) *Querier {
+	if e, ok := c.(Enabler); ok {
+		e.EnableStoreProofs()
+	}
+	return &Querier{
+		client:  c,
+		headers: h,
+		runtime: rootmulti.DefaultProofRuntime(),
+	}
+}
+
+// RawContractState returns the value of the key in the raw state of the given
+// contract at the latest verified header. It returns nil if the key is not
+// set.
+//
+// As the app hash of a header commits to the state after the previous block,
+// the returned state lags one block behind the latest verified header.
+func (q *Querier) RawContractState(ctx context.Context, contract string, key []byte) ([]byte, error) {
+	addr, err := types.AccAddressFromBech32(contract)
+	if err != nil {
+		return nil, fmt.Errorf("parsing contract address: %w", err)
+	}
+
+	h, err := q.headers.VerifiedHeader(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("verifying header: %w", err)
+	}
+	height := h.Height - 1
+
+	storeKey := append(wtypes.GetContractStorePrefix(addr), key...)
+	p, err := q.client.QueryStoreProof(ctx, wtypes.StoreKey, storeKey, height)
+	if err != nil {
+		return nil, fmt.Errorf("querying proof: %w", err)
+	} else if p.Height != height {
+		return nil, fmt.Errorf("%w: got height %d, expected %d", ErrInvalidProof, p.Height, height)
+	}
+
+	keyPath := merkle.KeyPath{}.
+		AppendKey([]byte(wtypes.StoreKey), merkle.KeyEncodingURL).
+		AppendKey(storeKey, merkle.KeyEncodingURL).
+		String()
+	if len(p.Value) == 0 {
+		err = q.runtime.VerifyAbsence(p.Proof, h.AppHash, keyPath)
+	} else {
+		err = q.runtime.VerifyValue(p.Proof, h.AppHash, keyPath, p.Value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+
+	if len(p.Value) == 0 {
+		return nil, nil
+	}
+	return p.Value, nil
+}
+
+// LightClientVerifier is a HeaderVerifier that verifies headers with a
+// Tendermint light client.
+type LightClientVerifier struct {
+	client *light.Client
+}
+
+var _ HeaderVerifier = &LightClientVerifier{}
+
+// NewLightClientVerifier creates a new verifier using the given light client.
+func NewLightClientVerifier(c *light.Client) *LightClientVerifier {
+	return &LightClientVerifier{client: c}
+}
+
+// VerifiedHeader returns the header at the given height, verified by the light
+// client. If the height is 0, the light client is updated to the latest
+// header of its primary.
+func (v *LightClientVerifier) VerifiedHeader(ctx context.Context, height int64) (tmproto.Header, error) {
+	var (
+		lb  *tmtypes.LightBlock
+		err error
+	)
+	if height == 0 {
+		lb, err = v.latest(ctx)
+	} else {
+		lb, err = v.client.VerifyLightBlockAtHeight(ctx, height, time.Now())
+	}
+	if err != nil {
+		return tmproto.Header{}, err
+	}
+	return *lb.Header.ToProto(), nil
+}
+
+// latest updates the light client and returns the latest trusted light block.
+func (v *LightClientVerifier) latest(ctx context.Context) (*tmtypes.LightBlock, error) {
+	lb, err := v.client.Update(ctx, time.Now())
+	if err != nil || lb != nil {
+		return lb, err
+	}
+
+	// The light client is up to date.
+	h, err := v.client.LastTrustedHeight()
+	if err != nil {
+		return nil, err
+	}
+	return v.client.TrustedLightBlock(h)
+}
@@ -0,0 +1,195 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+)
+
+type (
+	// Block represents a block produced by the simulated chain.
+	Block struct {
+		Header tmproto.Header
+		Events []abci.Event
+	}
+
+	// BlockHook is a function that is called on every block with the context
+	// of the block. Events emitted on the context's event manager are
+	// recorded in the block.
+	BlockHook func(ctx types.Context)
+)
+
+// AddBeginBlockHook registers a hook that is called at the beginning of every
+// block.
+func (c *Client) AddBeginBlockHook(h BlockHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.beginBlockHooks = append(c.beginBlockHooks, h)
+}
+
+// AddEndBlockHook registers a hook that is called at the end of every block.
+func (c *Client) AddEndBlockHook(h BlockHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.endBlockHooks = append(c.endBlockHooks, h)
+}
+
+const (
+	// blockSubscriptionBuffer is the number of blocks buffered per block
+	// subscription.
+	blockSubscriptionBuffer = 256
+	// blockHistorySize is the number of past blocks that are kept for
+	// queries by height.
+	blockHistorySize = 1024
+)
+
+// blockSubscription is a subscription to the blocks of a client.
+type blockSubscription struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	send   func(Block) bool // Sends a block without blocking.
+}
+
+// SubscribeBlocks returns a channel on which every block that is produced
+// after the call is published, including the events emitted within the block.
+// The channel is closed when the context is done.
+//
+// Blocks are published without waiting for the subscriber, so that a slow
+// subscriber does not stall block production. Up to 256 blocks are buffered.
+// If the subscriber falls further behind, the subscription is cancelled and
+// the channel is closed without publishing further blocks, so that the
+// subscriber never misses a block unnoticed.
+func (c *Client) SubscribeBlocks(ctx context.Context) <-chan Block {
+	sub := make(chan Block, blockSubscriptionBuffer)
+	ctx = c.subscribeBlocks(ctx, func(b Block) bool {
+		select {
+		case sub <- b:
+			return true
+		default:
+			return false
+		}
+	})
+	go func() {
+		<-ctx.Done()
+		// Blocks are only sent under the lock to active subscriptions.
+		c.subsMu.Lock()
+		defer c.subsMu.Unlock()
+		close(sub)
+	}()
+	return sub
+}
+
+// subscribeBlocks calls send with every block that is produced after the
+// call, in order. Send must not block and returns whether the block was
+// sent. The subscription is cancelled when the context is done or a block
+// cannot be sent. It returns the context of the subscription.
+func (c *Client) subscribeBlocks(ctx context.Context, send func(Block) bool) context.Context {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &blockSubscription{ctx: ctx, cancel: cancel, send: send}
+	c.subs[s] = struct{}{}
+	go func() {
+		<-ctx.Done()
+		c.subsMu.Lock()
+		defer c.subsMu.Unlock()
+		delete(c.subs, s)
+	}()
+	return ctx
+}
+
+// publish sends the block to all subscribers. Subscriptions that cannot
+// receive the block are cancelled.
+func (c *Client) publish(b Block) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+
+	for s := range c.subs {
+		if s.ctx.Err() == nil && !s.send(b) {
+			s.cancel()
+		}
+	}
+}
+
+// blockByHeight returns the block at the given height. The block at the
+// current height is still in production and only contains the events emitted
+// so far. Only the latest 1024 blocks before the current block are kept.
+func (c *Client) blockByHeight(height int64) (Block, error) {
+	if height == c.ctx.BlockHeight() {
+		return c.currentBlock(), nil
+	}
+
+	i := height - c.firstHeight
+	if i < 0 || i >= int64(len(c.blocks)) {
+		return Block{}, fmt.Errorf("unknown block height: %d", height)
+	}
+	return c.blocks[i], nil
+}
+
+// currentBlock returns the block that is currently in production.
+func (c *Client) currentBlock() Block {
+	return Block{
+		Header: c.ctx.BlockHeader(),
+		Events: c.ctx.EventManager().ABCIEvents(),
+	}
+}
+
+// produceBlock ends the current block, begins a new block with the time
+// returned by blockTime for the time of the ended block, and publishes the
+// ended block. Blocks are published in the order in which they are produced.
+func (c *Client) produceBlock(blockTime func(time.Time) time.Time) {
+	c.produceMu.Lock()
+	defer c.produceMu.Unlock()
+
+	c.mu.Lock()
+	b := c.nextBlock(blockTime(c.ctx.BlockTime()))
+	c.mu.Unlock()
+
+	c.publish(b)
+}
+
+// nextBlock ends the current block and begins a new block with the given
+// time. It returns the ended block. The caller must hold the lock.
+func (c *Client) nextBlock(t time.Time) Block {
+	for _, h := range c.endBlockHooks {
+		h(c.ctx)
+	}
+	b := c.currentBlock()
+	c.blocks = append(c.blocks, b)
+	if len(c.blocks) > blockHistorySize {
+		c.blocks = c.blocks[1:]
+		c.firstHeight++
+	}
+
+	header := c.ctx.BlockHeader()
+	header.AppHash = c.commit()
+	c.ctx = c.ctx.WithBlockHeader(header)
+
+	h := c.ctx.BlockHeight() + 1
+	c.ctx = c.ctx.WithBlockTime(t).WithBlockHeight(h).WithEventManager(types.NewEventManager())
+	for _, h := range c.beginBlockHooks {
+		h(c.ctx)
+	}
+	return b
+}
@@ -0,0 +1,134 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package simulation_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	"github.com/cosmos/cosmos-sdk/types"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/simulation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Blocks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c := simulation.NewTestClient(t)
+
+	var begun, ended []int64
+	c.AddBeginBlockHook(func(ctx types.Context) {
+		begun = append(begun, ctx.BlockHeight())
+	})
+	c.AddEndBlockHook(func(ctx types.Context) {
+		ended = append(ended, ctx.BlockHeight())
+		ctx.EventManager().EmitEvent(types.NewEvent("end_block"))
+	})
+
+	resp, err := c.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	require.NoError(t, err, "get latest block")
+	h := resp.Block.Header
+
+	blocks := c.SubscribeBlocks(ctx)
+	const numBlocks = 3
+	go func() {
+		for i := 1; i <= numBlocks; i++ {
+			c.SetBlockTime(h.Time.Add(time.Duration(i) * time.Minute))
+		}
+	}()
+
+	for i := int64(0); i < numBlocks; i++ {
+		b := <-blocks
+		assert.Equal(t, h.Height+i, b.Header.Height, "height")
+		require.Len(t, b.Events, 1, "events")
+		assert.Equal(t, "end_block", b.Events[0].Type, "event type")
+
+		resp, err := c.GetBlockByHeight(ctx, &tmservice.GetBlockByHeightRequest{Height: b.Header.Height})
+		require.NoError(t, err, "get block by height")
+		assert.Equal(t, b.Header, resp.Block.Header, "header")
+	}
+	assert.Equal(t, []int64{h.Height + 1, h.Height + 2, h.Height + 3}, begun, "begin block")
+	assert.Equal(t, []int64{h.Height, h.Height + 1, h.Height + 2}, ended, "end block")
+
+	_, err = c.GetBlockByHeight(ctx, &tmservice.GetBlockByHeightRequest{Height: h.Height + numBlocks + 1})
+	assert.Error(t, err, "future block")
+}
+
+func TestClient_SlowSubscriber(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c := simulation.NewTestClient(t)
+	resp, err := c.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	require.NoError(t, err, "get latest block")
+	h := resp.Block.Header
+
+	// The subscriber does not read until more blocks have been produced than
+	// are buffered or kept in the history.
+	blocks := c.SubscribeBlocks(ctx)
+	const numBlocks = 2000
+	for i := 1; i <= numBlocks; i++ {
+		c.SetBlockTime(h.Time.Add(time.Duration(i) * time.Minute))
+	}
+
+	n := 0
+	for b := range blocks {
+		assert.Equal(t, h.Height+int64(n), b.Header.Height, "height")
+		n++
+	}
+	assert.Greater(t, n, 0, "buffered blocks")
+	assert.Less(t, n, numBlocks, "subscription cancelled")
+	require.NoError(t, ctx.Err(), "channel closed before context done")
+
+	_, err = c.GetBlockByHeight(ctx, &tmservice.GetBlockByHeightRequest{Height: h.Height})
+	assert.Error(t, err, "block pruned from history")
+	_, err = c.GetBlockByHeight(ctx, &tmservice.GetBlockByHeightRequest{Height: h.Height + numBlocks - 1})
+	assert.NoError(t, err, "recent block")
+}
+
+func TestClient_ConcurrentBlocks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c := simulation.NewTestClient(t)
+	resp, err := c.GetLatestBlock(ctx, &tmservice.GetLatestBlockRequest{})
+	require.NoError(t, err, "get latest block")
+	h := resp.Block.Header
+
+	// Blocks produced concurrently are published in production order.
+	blocks := c.SubscribeBlocks(ctx)
+	const numProducers, numBlocks = 4, 50
+	var wg sync.WaitGroup
+	for p := 0; p < numProducers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= numBlocks; i++ {
+				c.SetBlockTime(h.Time.Add(time.Duration(i) * time.Minute))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < numProducers*numBlocks; i++ {
+		b := <-blocks
+		require.Equal(t, h.Height+int64(i), b.Header.Height, "height")
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"github.com/CosmWasm/wasmd/x/wasm"
 	"github.com/CosmWasm/wasmd/x/wasm/keeper"
 	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/store/iavl"
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
 	"github.com/cosmos/cosmos-sdk/types"
 	authkeeper "github.com/cosmos/cosmos-sdk/x/auth/keeper"
 	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
@@ -33,12 +35,24 @@ import (
 
 // Client is a Cosmos client that can be used in a simulation environment.
 type Client struct {
-	chainAccount types.AccAddress
-	msgHandler   types.Handler
-	ctx          types.Context
-	keepers      keeper.TestKeepers
-	mu           sync.RWMutex
-	stopTick     chan struct{}
+	chainAccount    types.AccAddress
+	msgHandler      types.Handler
+	ctx             types.Context
+	keepers         keeper.TestKeepers
+	mu              sync.RWMutex
+	produceMu       sync.Mutex // Held while a block is produced and published.
+	stopTick        chan struct{}
+	blocks          []Block // The latest produced blocks, starting at firstHeight.
+	firstHeight     int64
+	beginBlockHooks []BlockHook
+	endBlockHooks   []BlockHook
+	subs            map[*blockSubscription]struct{}
+	subsMu          sync.Mutex
+	snapshots       []snapshot
+	lastSnapshotID  SnapshotID
+	proofs          *iavl.Store                     // The store providing Merkle proofs, nil if not enabled.
+	commits         map[int64]storetypes.CommitInfo // The commits of the proof store by block height.
+	numTxs          int
 }
 
 var _ client.Client = &Client{}
@@ -58,8 +72,11 @@ func NewClient(ctx types.Context, acc types.AccAddress, handler types.Handler, k
 	return &Client{
 		chainAccount: acc,
 		msgHandler:   handler,
-		ctx:          ctx,
+		ctx:          ctx.WithEventManager(types.NewEventManager()),
 		keepers:      keepers,
+		firstHeight:  ctx.BlockHeight(),
+		subs:         make(map[*blockSubscription]struct{}),
+		commits:      make(map[int64]storetypes.CommitInfo),
 	}
 }
 
@@ -99,12 +116,10 @@ func (c *Client) BlockTime() time.Time {
 	return c.ctx.BlockTime()
 }
 
-// SetBlockTime sets the block time.
+// SetBlockTime ends the current block and begins a new block with the given
+// block time.
 func (c *Client) SetBlockTime(t time.Time) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.ctx = c.ctx.WithBlockTime(t).WithBlockHeight(c.ctx.BlockHeight() + 1)
+	c.produceBlock(func(time.Time) time.Time { return t })
 }
 
 // StartTicking starts the auto-ticking process. The process will create a
@@ -127,13 +142,9 @@ func (c *Client) StartTicking(tickInterval time.Duration, timeAddedPerTick time.
 				c.mu.Unlock()
 				return
 			case <-time.After(tickInterval):
-				func() {
-					c.mu.Lock()
-					defer c.mu.Unlock()
-					t := c.ctx.BlockTime().Add(timeAddedPerTick)
-					h := c.ctx.BlockHeight() + 1
-					c.ctx = c.ctx.WithBlockTime(t).WithBlockHeight(h)
-				}()
+				c.produceBlock(func(t time.Time) time.Time {
+					return t.Add(timeAddedPerTick)
+				})
 			}
 		}
 	}()
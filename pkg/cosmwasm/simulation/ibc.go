@@ -0,0 +1,155 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/CosmWasm/wasmd/x/wasm/keeper"
+	wasmvmtypes "github.com/CosmWasm/wasmvm/types"
+	"github.com/cosmos/cosmos-sdk/types"
+	channeltypes "github.com/cosmos/cosmos-sdk/x/ibc/core/04-channel/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+)
+
+// Packet is an IBC packet sent by a contract on a simulated chain.
+type Packet struct {
+	Sequence         uint64
+	SrcPort          string
+	SrcChannel       string
+	DstPort          string
+	DstChannel       string
+	Data             []byte
+	TimeoutTimestamp uint64 // In nanoseconds since the epoch. Zero means no timeout.
+}
+
+// Event returns the event that is emitted when the packet is sent.
+func (p Packet) Event() types.Event {
+	return types.NewEvent(channeltypes.EventTypeSendPacket,
+		types.NewAttribute(channeltypes.AttributeKeyData, string(p.Data)),
+		types.NewAttribute(channeltypes.AttributeKeyTimeoutTimestamp, strconv.FormatUint(p.TimeoutTimestamp, 10)),
+		types.NewAttribute(channeltypes.AttributeKeySequence, strconv.FormatUint(p.Sequence, 10)),
+		types.NewAttribute(channeltypes.AttributeKeySrcPort, p.SrcPort),
+		types.NewAttribute(channeltypes.AttributeKeySrcChannel, p.SrcChannel),
+		types.NewAttribute(channeltypes.AttributeKeyDstPort, p.DstPort),
+		types.NewAttribute(channeltypes.AttributeKeyDstChannel, p.DstChannel),
+	)
+}
+
+// SentPackets returns the packets that were sent in the block.
+func SentPackets(b Block) ([]Packet, error) {
+	var packets []Packet
+	for _, e := range b.Events {
+		if e.Type != channeltypes.EventTypeSendPacket {
+			continue
+		}
+		p, err := parsePacket(e)
+		if err != nil {
+			return nil, fmt.Errorf("parsing packet: %w", err)
+		}
+		packets = append(packets, p)
+	}
+	return packets, nil
+}
+
+func parsePacket(e abci.Event) (p Packet, err error) {
+	for _, a := range e.Attributes {
+		v := string(a.Value)
+		switch string(a.Key) {
+		case channeltypes.AttributeKeyData:
+			p.Data = []byte(v)
+		case channeltypes.AttributeKeyTimeoutTimestamp:
+			p.TimeoutTimestamp, err = strconv.ParseUint(v, 10, 64)
+		case channeltypes.AttributeKeySequence:
+			p.Sequence, err = strconv.ParseUint(v, 10, 64)
+		case channeltypes.AttributeKeySrcPort:
+			p.SrcPort = v
+		case channeltypes.AttributeKeySrcChannel:
+			p.SrcChannel = v
+		case channeltypes.AttributeKeyDstPort:
+			p.DstPort = v
+		case channeltypes.AttributeKeyDstChannel:
+			p.DstChannel = v
+		}
+		if err != nil {
+			return Packet{}, fmt.Errorf("attribute %s: %w", a.Key, err)
+		}
+	}
+	return p, nil
+}
+
+func (p Packet) wasm() wasmvmtypes.IBCPacket {
+	return wasmvmtypes.IBCPacket{
+		Data:     p.Data,
+		Src:      wasmvmtypes.IBCEndpoint{PortID: p.SrcPort, ChannelID: p.SrcChannel},
+		Dest:     wasmvmtypes.IBCEndpoint{PortID: p.DstPort, ChannelID: p.DstChannel},
+		Sequence: p.Sequence,
+		Timeout:  wasmvmtypes.IBCTimeout{Timestamp: p.TimeoutTimestamp},
+	}
+}
+
+// ReceivePacket delivers the packet to the contract bound to its destination
+// port and returns the acknowledgement written by the contract. The packet
+// is received within the current block.
+func (c *Client) ReceivePacket(ctx context.Context, p Packet) (ack []byte, err error) {
+	err = c.handlePacket(ctx, p.DstPort, func(_ctx types.Context, contract types.AccAddress) (err error) {
+		ack, err = c.keepers.WasmKeeper.OnRecvPacket(_ctx, contract, wasmvmtypes.IBCPacketReceiveMsg{Packet: p.wasm()})
+		return err
+	})
+	return ack, err
+}
+
+// AcknowledgePacket delivers the acknowledgement of the packet to the
+// contract bound to its source port.
+func (c *Client) AcknowledgePacket(ctx context.Context, p Packet, ack []byte) error {
+	return c.handlePacket(ctx, p.SrcPort, func(_ctx types.Context, contract types.AccAddress) error {
+		return c.keepers.WasmKeeper.OnAckPacket(_ctx, contract, wasmvmtypes.IBCPacketAckMsg{
+			Acknowledgement: wasmvmtypes.IBCAcknowledgement{Data: ack},
+			OriginalPacket:  p.wasm(),
+		})
+	})
+}
+
+// TimeoutPacket notifies the contract bound to the source port of the packet
+// that the packet timed out.
+func (c *Client) TimeoutPacket(ctx context.Context, p Packet) error {
+	return c.handlePacket(ctx, p.SrcPort, func(_ctx types.Context, contract types.AccAddress) error {
+		return c.keepers.WasmKeeper.OnTimeoutPacket(_ctx, contract, wasmvmtypes.IBCPacketTimeoutMsg{Packet: p.wasm()})
+	})
+}
+
+// handlePacket calls the handler with the contract bound to the port within
+// the current block. Like messages, state changes are only applied and
+// events are only recorded if the handler succeeds.
+func (c *Client) handlePacket(ctx context.Context, port string, handle func(types.Context, types.AccAddress) error) error {
+	contract, err := keeper.ContractFromPortID(port)
+	if err != nil {
+		return fmt.Errorf("port %q: %w", port, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ctx, write := c.ctx.WithContext(ctx).CacheContext()
+	_ctx = _ctx.WithGasMeter(types.NewInfiniteGasMeter()).WithEventManager(types.NewEventManager())
+	if err := handle(_ctx, contract); err != nil {
+		return err
+	}
+	write()
+	c.ctx.EventManager().EmitEvents(_ctx.EventManager().Events())
+	return nil
+}
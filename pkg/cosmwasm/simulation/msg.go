@@ -3,18 +3,56 @@ package simulation
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/types"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"github.com/tendermint/tendermint/crypto/tmhash"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
-// StoreCode stores the code of a smart contract on the ledger.
-func (c *Client) StoreCode(ctx context.Context, in *wtypes.MsgStoreCode, opts ...grpc.CallOption) (*wtypes.MsgStoreCodeResponse, error) {
+// handleMsg executes the message within the current block. State changes are
+// only applied and events are only recorded if the execution succeeds. The
+// transaction hash, block height and gas used are reported in the headers
+// requested by the call options.
+func (c *Client) handleMsg(ctx context.Context, msg types.Msg, opts []grpc.CallOption) (*types.Result, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	_ctx := c.ctx.WithContext(ctx)
-	res, err := c.msgHandler(_ctx, in)
+	_ctx, write := c.ctx.WithContext(ctx).CacheContext()
+	_ctx = _ctx.WithGasMeter(types.NewInfiniteGasMeter())
+	res, err := c.msgHandler(_ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	write()
+	c.ctx.EventManager().EmitEvents(res.GetEvents())
+
+	c.numTxs++
+	hash := tmhash.Sum([]byte(fmt.Sprintf("%d:%v", c.numTxs, msg)))
+	setHeader(opts, metadata.Pairs(
+		client.TxHashHeader, fmt.Sprintf("%X", hash),
+		grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(c.ctx.BlockHeight(), 10),
+		client.GasUsedHeader, strconv.FormatUint(_ctx.GasMeter().GasConsumed(), 10),
+	))
+	return res, nil
+}
+
+// setHeader sets the headers requested by the call options.
+func setHeader(opts []grpc.CallOption, md metadata.MD) {
+	for _, opt := range opts {
+		if h, ok := opt.(grpc.HeaderCallOption); ok {
+			*h.HeaderAddr = md
+		}
+	}
+}
+
+// StoreCode stores the code of a smart contract on the ledger.
+func (c *Client) StoreCode(ctx context.Context, in *wtypes.MsgStoreCode, opts ...grpc.CallOption) (*wtypes.MsgStoreCodeResponse, error) {
+	res, err := c.handleMsg(ctx, in, opts)
 	if err != nil {
 		return nil, fmt.Errorf("handling message: %w", err)
 	}
@@ -27,13 +65,9 @@ func (c *Client) StoreCode(ctx context.Context, in *wtypes.MsgStoreCode, opts ..
 	return &resp, nil
 }
 
-//  InstantiateContract creates a new smart contract instance for the given code id.
+// InstantiateContract creates a new smart contract instance for the given code id.
 func (c *Client) InstantiateContract(ctx context.Context, in *wtypes.MsgInstantiateContract, opts ...grpc.CallOption) (*wtypes.MsgInstantiateContractResponse, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	_ctx := c.ctx.WithContext(ctx)
-	res, err := c.msgHandler(_ctx, in)
+	res, err := c.handleMsg(ctx, in, opts)
 	if err != nil {
 		return nil, fmt.Errorf("handling message: %w", err)
 	}
@@ -48,11 +82,7 @@ func (c *Client) InstantiateContract(ctx context.Context, in *wtypes.MsgInstanti
 
 // ExecuteContract executes a function on a contract.
 func (c *Client) ExecuteContract(ctx context.Context, in *wtypes.MsgExecuteContract, opts ...grpc.CallOption) (*wtypes.MsgExecuteContractResponse, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	_ctx := c.ctx.WithContext(ctx)
-	res, err := c.msgHandler(_ctx, in)
+	res, err := c.handleMsg(ctx, in, opts)
 	if err != nil {
 		return nil, fmt.Errorf("handling message: %w", err)
 	}
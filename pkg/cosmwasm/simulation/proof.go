@@ -0,0 +1,164 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package simulation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/store/iavl"
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	"github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/proof"
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+var (
+	_ proof.Client         = &Client{}
+	_ proof.Enabler        = &Client{}
+	_ proof.HeaderVerifier = &Client{}
+)
+
+// newProofStore creates the store that is committed at the end of each block
+// to provide Merkle proofs.
+//
+// The stores of the simulated ledger share a single database and therefore
+// cannot be committed. Instead, the contract state is mirrored into a separate
+// store, which is the only store the app hash of the simulated chain commits
+// to.
+func newProofStore() *iavl.Store {
+	s, err := iavl.LoadStore(dbm.NewMemDB(), storetypes.CommitID{}, false)
+	if err != nil {
+		panic(err) // Loading an empty store does not fail.
+	}
+	return s.(*iavl.Store)
+}
+
+// EnableStoreProofs makes the chain commit to its contract state, so that
+// QueryStoreProof can provide proofs for the state after the current block.
+//
+// Committing copies the state of all contracts at the end of every block, so
+// it is disabled by default. It is enabled by proof.NewQuerier and on the first
+// use of QueryStoreProof.
+func (c *Client) EnableStoreProofs() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.enableStoreProofs()
+}
+
+// enableStoreProofs enables store proofs. The caller must hold the lock.
+func (c *Client) enableStoreProofs() {
+	if c.proofs == nil {
+		c.proofs = newProofStore()
+	}
+}
+
+// commit mirrors the state of all contracts into the proof store, commits it,
+// and returns the resulting app hash. If store proofs are not enabled, it
+// returns nil. The caller must hold the lock.
+func (c *Client) commit() []byte {
+	if c.proofs == nil {
+		return nil
+	}
+	k := c.keepers.WasmKeeper
+	state := make(map[string][]byte)
+	k.IterateContractInfo(c.ctx, func(addr types.AccAddress, _ wtypes.ContractInfo) bool {
+		prefix := wtypes.GetContractStorePrefix(addr)
+		iter := k.GetContractState(c.ctx, addr)
+		defer iter.Close()
+		for ; iter.Valid(); iter.Next() {
+			state[string(prefix)+string(iter.Key())] = iter.Value()
+		}
+		return false
+	})
+
+	var removed [][]byte
+	iter := c.proofs.Iterator(nil, nil)
+	for ; iter.Valid(); iter.Next() {
+		if _, ok := state[string(iter.Key())]; !ok {
+			removed = append(removed, iter.Key())
+		}
+	}
+	iter.Close()
+	for _, key := range removed {
+		c.proofs.Delete(key)
+	}
+	for key, value := range state {
+		if !bytes.Equal(c.proofs.Get([]byte(key)), value) {
+			c.proofs.Set([]byte(key), value)
+		}
+	}
+
+	id := c.proofs.Commit()
+	info := storetypes.CommitInfo{
+		Version:    id.Version,
+		StoreInfos: []storetypes.StoreInfo{{Name: wtypes.StoreKey, CommitId: id}},
+	}
+	c.commits[c.ctx.BlockHeight()] = info
+	return info.Hash()
+}
+
+// QueryStoreProof queries the value of the key in the store with the given
+// name in the state after the block at the given height, together with a
+// Merkle proof. Only the contract state of the wasm store can be queried.
+//
+// If store proofs are not enabled yet, they are enabled and only the state
+// after the current block can be queried. Querying the state at a height that
+// has not been committed returns proof.ErrNotCommitted.
+func (c *Client) QueryStoreProof(ctx context.Context, storeName string, key []byte, height int64) (proof.StoreProof, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.enableStoreProofs()
+	if storeName != wtypes.StoreKey {
+		return proof.StoreProof{}, fmt.Errorf("unknown store: %s", storeName)
+	}
+	info, ok := c.commits[height]
+	if !ok {
+		return proof.StoreProof{}, fmt.Errorf("%w: height %d", proof.ErrNotCommitted, height)
+	}
+
+	res := c.proofs.Query(abci.RequestQuery{
+		Path:   "/key",
+		Data:   key,
+		Height: info.Version,
+		Prove:  true,
+	})
+	if !res.IsOK() {
+		return proof.StoreProof{}, sdkerrors.ABCIError(res.Codespace, res.Code, res.Log)
+	}
+	res.ProofOps.Ops = append(res.ProofOps.Ops, info.ProofOp(storeName))
+	return proof.StoreProof{Value: res.Value, Proof: res.ProofOps, Height: height}, nil
+}
+
+// VerifiedHeader returns the header at the given height, or the header of the
+// block in production if the height is 0. The headers of the simulated chain
+// are trusted.
+func (c *Client) VerifiedHeader(ctx context.Context, height int64) (tmproto.Header, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if height == 0 {
+		return c.ctx.BlockHeader(), nil
+	}
+	b, err := c.blockByHeight(height)
+	return b.Header, err
+}
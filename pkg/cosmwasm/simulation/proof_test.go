@@ -0,0 +1,79 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package simulation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/proof"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/simulation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_StoreProofs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c := simulation.NewTestClient(t)
+	appHash := func() []byte {
+		h, err := c.VerifiedHeader(ctx, 0)
+		require.NoError(t, err)
+		return h.AppHash
+	}
+	nextBlock := func() int64 {
+		h, err := c.VerifiedHeader(ctx, 0)
+		require.NoError(t, err)
+		c.SetBlockTime(h.Time.Add(time.Minute))
+		return h.Height
+	}
+
+	height := nextBlock()
+	assert.Empty(t, appHash(), "not committed by default")
+
+	// The first query enables proofs, which are available from the end of
+	// the current block on.
+	_, err := c.QueryStoreProof(ctx, wtypes.StoreKey, []byte("key"), height)
+	assert.ErrorIs(t, err, proof.ErrNotCommitted, "state before enabling")
+	height = nextBlock()
+	assert.NotEmpty(t, appHash(), "committed")
+	p, err := c.QueryStoreProof(ctx, wtypes.StoreKey, []byte("key"), height)
+	require.NoError(t, err)
+	assert.Equal(t, height, p.Height)
+}
+
+func TestClient_QuerierEnablesProofs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c := simulation.NewTestClient(t)
+	q := proof.NewQuerier(c, c)
+
+	// The state before the querier was created is not committed.
+	_, err := q.RawContractState(ctx, c.Account().String(), []byte("key"))
+	assert.ErrorIs(t, err, proof.ErrNotCommitted, "state before querier")
+
+	// The querier enabled the proofs, so the state after the current block
+	// is committed.
+	h, err := c.VerifiedHeader(ctx, 0)
+	require.NoError(t, err)
+	c.SetBlockTime(h.Time.Add(time.Minute))
+	p, err := c.QueryStoreProof(ctx, wtypes.StoreKey, []byte("key"), h.Height)
+	require.NoError(t, err)
+	assert.Equal(t, h.Height, p.Height)
+}
@@ -11,7 +11,12 @@ import (
 
 // ContractInfo gets the contract meta data.
 func (c *Client) ContractInfo(ctx context.Context, in *wtypes.QueryContractInfoRequest, opts ...grpc.CallOption) (*wtypes.QueryContractInfoResponse, error) {
-	panic("not implemented")
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	q := keeper.Querier(c.keepers.WasmKeeper)
+	_ctx := c.ctx.WithContext(ctx)
+	return q.ContractInfo(types.WrapSDKContext(_ctx), in)
 }
 
 // ContractHistory gets the contract code history.
@@ -26,12 +31,22 @@ func (c *Client) ContractsByCode(ctx context.Context, in *wtypes.QueryContractsB
 
 // AllContractState gets all raw store data for a single contract.
 func (c *Client) AllContractState(ctx context.Context, in *wtypes.QueryAllContractStateRequest, opts ...grpc.CallOption) (*wtypes.QueryAllContractStateResponse, error) {
-	panic("not implemented")
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	q := keeper.Querier(c.keepers.WasmKeeper)
+	_ctx := c.ctx.WithContext(ctx)
+	return q.AllContractState(types.WrapSDKContext(_ctx), in)
 }
 
 // RawContractState gets a single key from the raw store data of a contract.
 func (c *Client) RawContractState(ctx context.Context, in *wtypes.QueryRawContractStateRequest, opts ...grpc.CallOption) (*wtypes.QueryRawContractStateResponse, error) {
-	panic("not implemented")
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	q := keeper.Querier(c.keepers.WasmKeeper)
+	_ctx := c.ctx.WithContext(ctx)
+	return q.RawContractState(types.WrapSDKContext(_ctx), in)
 }
 
 // SmartContractState performs a smart contract query.
@@ -49,10 +64,20 @@ func (c *Client) SmartContractState(ctx context.Context, in *wtypes.QuerySmartCo
 
 // Code gets the binary code and metadata for a code id.
 func (c *Client) Code(ctx context.Context, in *wtypes.QueryCodeRequest, opts ...grpc.CallOption) (*wtypes.QueryCodeResponse, error) {
-	panic("not implemented")
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	q := keeper.Querier(c.keepers.WasmKeeper)
+	_ctx := c.ctx.WithContext(ctx)
+	return q.Code(types.WrapSDKContext(_ctx), in)
 }
 
 // Codes gets the metadata for all stored wasm codes.
 func (c *Client) Codes(ctx context.Context, in *wtypes.QueryCodesRequest, opts ...grpc.CallOption) (*wtypes.QueryCodesResponse, error) {
-	panic("not implemented")
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	q := keeper.Querier(c.keepers.WasmKeeper)
+	_ctx := c.ctx.WithContext(ctx)
+	return q.Codes(types.WrapSDKContext(_ctx), in)
 }
@@ -0,0 +1,239 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package simulation
+
+import (
+	"context"
+	"fmt"
+)
+
+// PacketHandler handles the IBC packets that are relayed to and from a chain.
+type PacketHandler interface {
+	ReceivePacket(ctx context.Context, p Packet) (ack []byte, err error)
+	AcknowledgePacket(ctx context.Context, p Packet, ack []byte) error
+	TimeoutPacket(ctx context.Context, p Packet) error
+}
+
+var _ PacketHandler = &Client{}
+
+type (
+	// Relayer is a mock IBC relayer between two simulated chains. It picks up
+	// the packets sent in the blocks of one chain and delivers them to the
+	// other chain, and it delivers the acknowledgements and timeouts back.
+	//
+	// The relayer does not verify proofs and does not require an IBC
+	// connection between the chains. Packets are delivered to the contracts
+	// bound to their ports via the wasm keeper.
+	Relayer struct {
+		ends     [2]relayEnd
+		handlers map[*Client]PacketHandler
+		delay    int
+		drop     func(Packet) bool
+	}
+
+	relayEnd struct {
+		c       *Client
+		h       PacketHandler
+		pending []delivery // Deliveries to the chain.
+	}
+
+	// delivery is a packet, acknowledgement or timeout waiting to be
+	// delivered.
+	delivery struct {
+		kind   deliveryKind
+		p      Packet
+		ack    []byte
+		blocks int // Blocks of the destination chain still to wait.
+	}
+
+	deliveryKind int
+
+	// RelayerOpt is an option for the relayer.
+	RelayerOpt func(*Relayer)
+)
+
+const (
+	deliverPacket deliveryKind = iota
+	deliverAck
+	deliverTimeout
+	lostPacket // Dropped packet that times out eventually.
+)
+
+// RelayerDelayOpt delays each delivery by the given number of blocks of the
+// receiving chain. By default, a packet is delivered in the first block of
+// the receiving chain after the block in which it was sent.
+func RelayerDelayOpt(blocks int) RelayerOpt {
+	return func(r *Relayer) {
+		r.delay = blocks
+	}
+}
+
+// RelayerDropOpt makes the relayer drop the packets for which drop returns
+// true. Dropped packets are never received. If they have a timeout, the
+// timeout is delivered to the sender once the receiving chain has passed it.
+func RelayerDropOpt(drop func(Packet) bool) RelayerOpt {
+	return func(r *Relayer) {
+		r.drop = drop
+	}
+}
+
+// RelayerPacketHandlerOpt sets the handler of the packets relayed to and from
+// chain c. By default, the chain's client is used, which passes the packets
+// to the contracts bound to their ports.
+func RelayerPacketHandlerOpt(c *Client, h PacketHandler) RelayerOpt {
+	return func(r *Relayer) {
+		r.handlers[c] = h
+	}
+}
+
+// NewRelayer creates a relayer between chains a and b.
+func NewRelayer(a, b *Client, opts ...RelayerOpt) *Relayer {
+	r := &Relayer{
+		ends:     [2]relayEnd{{c: a, h: a}, {c: b, h: b}},
+		handlers: make(map[*Client]PacketHandler),
+		drop:     func(Packet) bool { return false },
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	for i := range r.ends {
+		if h, ok := r.handlers[r.ends[i].c]; ok {
+			r.ends[i].h = h
+		}
+	}
+	return r
+}
+
+// Start starts relaying the packets that are sent in the blocks produced
+// after the call. The relayer stops when the context is done or a delivery
+// fails. The returned channel receives the error that stopped the relayer.
+//
+// The blocks of both chains are relayed in the order in which they are
+// produced. If the relayer falls behind by more than 256 blocks, it stops.
+func (r *Relayer) Start(ctx context.Context) <-chan error {
+	ctx, cancel := context.WithCancel(ctx)
+	blocks := make(chan chainBlock, blockSubscriptionBuffer)
+	var subs [2]context.Context
+	for i := range r.ends {
+		i := i
+		subs[i] = r.ends[i].c.subscribeBlocks(ctx, func(b Block) bool {
+			select {
+			case blocks <- chainBlock{chain: i, b: b}:
+				return true
+			default:
+				return false
+			}
+		})
+	}
+	errs := make(chan error, 1)
+	go func() {
+		defer cancel()
+		errs <- r.run(ctx, blocks, subs)
+	}()
+	return errs
+}
+
+// chainBlock is a block of the chain with the given index.
+type chainBlock struct {
+	chain int
+	b     Block
+}
+
+func (r *Relayer) run(ctx context.Context, blocks <-chan chainBlock, subs [2]context.Context) error {
+	for {
+		var cb chainBlock
+		select {
+		case cb = <-blocks:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-subs[0].Done():
+			return r.cancelled(ctx, 0)
+		case <-subs[1].Done():
+			return r.cancelled(ctx, 1)
+		}
+		i, b := cb.chain, cb.b
+
+		packets, err := SentPackets(b)
+		if err != nil {
+			return fmt.Errorf("chain %d, block %d: %w", i, b.Header.Height, err)
+		}
+		for _, p := range packets {
+			d := delivery{kind: deliverPacket, p: p, blocks: r.delay}
+			if r.drop(p) {
+				d.kind = lostPacket
+			}
+			r.ends[1-i].pending = append(r.ends[1-i].pending, d)
+		}
+		if err := r.deliver(ctx, i); err != nil {
+			return fmt.Errorf("chain %d: %w", i, err)
+		}
+	}
+}
+
+// cancelled returns the error for the cancelled block subscription of chain
+// i.
+func (r *Relayer) cancelled(ctx context.Context, i int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("chain %d: block subscription cancelled", i)
+}
+
+// deliver delivers the pending deliveries to chain i that are due after the
+// chain has produced a block.
+func (r *Relayer) deliver(ctx context.Context, i int) error {
+	to, from := &r.ends[i], &r.ends[1-i]
+	pending := to.pending
+	to.pending = nil
+	for _, d := range pending {
+		if d.blocks > 0 {
+			d.blocks--
+			to.pending = append(to.pending, d)
+			continue
+		}
+
+		switch d.kind {
+		case deliverPacket, lostPacket:
+			if r.timedOut(to.c, d.p) {
+				from.pending = append(from.pending, delivery{kind: deliverTimeout, p: d.p, blocks: r.delay})
+				continue
+			}
+			if d.kind == lostPacket {
+				to.pending = append(to.pending, d)
+				continue
+			}
+			ack, err := to.h.ReceivePacket(ctx, d.p)
+			if err != nil {
+				return fmt.Errorf("receiving packet %d: %w", d.p.Sequence, err)
+			}
+			from.pending = append(from.pending, delivery{kind: deliverAck, p: d.p, ack: ack, blocks: r.delay})
+		case deliverAck:
+			if err := to.h.AcknowledgePacket(ctx, d.p, d.ack); err != nil {
+				return fmt.Errorf("acknowledging packet %d: %w", d.p.Sequence, err)
+			}
+		case deliverTimeout:
+			if err := to.h.TimeoutPacket(ctx, d.p); err != nil {
+				return fmt.Errorf("timing out packet %d: %w", d.p.Sequence, err)
+			}
+		}
+	}
+	return nil
+}
+
+// timedOut returns whether the receiving chain c has passed the timeout of
+// the packet.
+func (r *Relayer) timedOut(c *Client, p Packet) bool {
+	return p.TimeoutTimestamp != 0 && uint64(c.BlockTime().UnixNano()) >= p.TimeoutTimestamp
+}
@@ -0,0 +1,191 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package simulation_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/types"
+	"github.com/perun-network/perun-cosmwasm-backend/channel/test"
+	"github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm/simulation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// handled is a packet, acknowledgement or timeout handled by a recorder.
+type handled struct {
+	kind string
+	p    simulation.Packet
+	ack  []byte
+}
+
+// recorder is a packet handler that records the packets it handles.
+type recorder chan handled
+
+func (r recorder) ReceivePacket(_ context.Context, p simulation.Packet) ([]byte, error) {
+	r <- handled{kind: "receive", p: p}
+	return []byte("ack"), nil
+}
+
+func (r recorder) AcknowledgePacket(_ context.Context, p simulation.Packet, ack []byte) error {
+	r <- handled{kind: "acknowledge", p: p, ack: ack}
+	return nil
+}
+
+func (r recorder) TimeoutPacket(_ context.Context, p simulation.Packet) error {
+	r <- handled{kind: "timeout", p: p}
+	return nil
+}
+
+// next returns the next handled packet or fails the test.
+func (r recorder) next(t *testing.T) handled {
+	t.Helper()
+	select {
+	case h := <-r:
+		return h
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	return handled{}
+}
+
+// none asserts that no packet is handled for a short while.
+func (r recorder) none(t *testing.T) {
+	t.Helper()
+	select {
+	case h := <-r:
+		t.Fatalf("unexpected %s", h.kind)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// sender sends packets from a simulated chain by emitting their events at the
+// end of the next block.
+type sender struct {
+	mu      sync.Mutex
+	packets []simulation.Packet
+}
+
+func newSender(c *simulation.Client) *sender {
+	s := new(sender)
+	c.AddEndBlockHook(func(ctx types.Context) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, p := range s.packets {
+			ctx.EventManager().EmitEvent(p.Event())
+		}
+		s.packets = nil
+	})
+	return s
+}
+
+func (s *sender) send(p simulation.Packet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.packets = append(s.packets, p)
+}
+
+// step produces a block on the chain.
+func step(c *simulation.Client, d time.Duration) {
+	c.SetBlockTime(c.BlockTime().Add(d))
+}
+
+func TestRelayer(t *testing.T) {
+	packet := simulation.Packet{
+		Sequence:   1,
+		SrcPort:    "wasm.a",
+		SrcChannel: "channel-0",
+		DstPort:    "wasm.b",
+		DstChannel: "channel-1",
+		Data:       []byte(`{"conclude":{}}`),
+	}
+	setup := func(t *testing.T, opts ...simulation.RelayerOpt) (a, b *simulation.Client, s *sender, recA, recB recorder) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		a, b = simulation.NewTestClient(t), simulation.NewTestClient(t)
+		s, recA, recB = newSender(a), make(recorder, 1), make(recorder, 1)
+		opts = append(opts, simulation.RelayerPacketHandlerOpt(a, recA), simulation.RelayerPacketHandlerOpt(b, recB))
+		simulation.NewRelayer(a, b, opts...).Start(ctx)
+		return
+	}
+
+	t.Run("deliver", func(t *testing.T) {
+		a, b, s, recA, recB := setup(t)
+		s.send(packet)
+		step(a, time.Second)
+		step(b, time.Second)
+		assert.Equal(t, handled{kind: "receive", p: packet}, recB.next(t))
+		step(a, time.Second)
+		assert.Equal(t, handled{kind: "acknowledge", p: packet, ack: []byte("ack")}, recA.next(t))
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		a, b, s, _, recB := setup(t, simulation.RelayerDelayOpt(1))
+		s.send(packet)
+		step(a, time.Second)
+		step(b, time.Second)
+		recB.none(t)
+		step(b, time.Second)
+		assert.Equal(t, "receive", recB.next(t).kind)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		drop := func(simulation.Packet) bool { return true }
+		a, b, s, recA, recB := setup(t, simulation.RelayerDropOpt(drop))
+		p := packet
+		p.TimeoutTimestamp = uint64(b.BlockTime().Add(time.Minute).UnixNano())
+		s.send(p)
+		step(a, time.Second)
+		step(b, time.Second)
+		step(a, time.Second)
+		recA.none(t)
+		step(b, time.Hour)
+		step(a, time.Second)
+		assert.Equal(t, handled{kind: "timeout", p: p}, recA.next(t))
+		recB.none(t)
+	})
+}
+
+// TestRelayer_Contract tests that packets are delivered to the contract bound
+// to the destination port. The Perun contract does not support IBC yet, so
+// the delivery fails.
+func TestRelayer_Contract(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	a := simulation.NewTestClient(t)
+	b, contract := test.NewTestClientWithContract(ctx, t)
+	s := newSender(a)
+	errs := simulation.NewRelayer(a, b).Start(ctx)
+
+	s.send(simulation.Packet{
+		Sequence: 1,
+		SrcPort:  "wasm.a",
+		DstPort:  "wasm." + contract.Address(),
+		Data:     []byte(`{}`),
+	})
+	step(a, time.Second)
+	step(b, time.Second)
+	select {
+	case err := <-errs:
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Missing export ibc_packet_receive")
+	case <-ctx.Done():
+		t.Fatal("timeout")
+	}
+}
@@ -13,9 +13,9 @@ func (c *Client) GetNodeInfo(ctx context.Context, in *tmservice.GetNodeInfoReque
 	panic("not implemented")
 }
 
-// GetSyncing queries node syncing.
+// GetSyncing queries node syncing. The simulated node is always in sync.
 func (c *Client) GetSyncing(ctx context.Context, in *tmservice.GetSyncingRequest, opts ...grpc.CallOption) (*tmservice.GetSyncingResponse, error) {
-	panic("not implemented")
+	return &tmservice.GetSyncingResponse{Syncing: false}, nil
 }
 
 // GetLatestBlock returns the latest block.
@@ -34,7 +34,20 @@ func (c *Client) GetLatestBlock(ctx context.Context, in *tmservice.GetLatestBloc
 
 // GetBlockByHeight queries block for given height.
 func (c *Client) GetBlockByHeight(ctx context.Context, in *tmservice.GetBlockByHeightRequest, opts ...grpc.CallOption) (*tmservice.GetBlockByHeightResponse, error) {
-	panic("not implemented")
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	b, err := c.blockByHeight(in.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := tmservice.GetBlockByHeightResponse{
+		Block: &types.Block{
+			Header: b.Header, // We only need the header for simulation.
+		},
+	}
+	return &resp, nil
 }
 
 // GetLatestValidatorSet queries latest validator-set.
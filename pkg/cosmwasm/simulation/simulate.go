@@ -0,0 +1,47 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package simulation
+
+import (
+	"context"
+	"fmt"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/types"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+)
+
+var _ client.Simulator = &Client{}
+
+// SimulateExecuteContracts simulates executing the messages in the given
+// order, each on the state resulting from the previous ones, and returns the
+// gas used by each of them. The state of the simulated ledger is not
+// changed.
+func (c *Client) SimulateExecuteContracts(ctx context.Context, msgs []*wtypes.MsgExecuteContract) ([]uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ctx, _ := c.ctx.WithContext(ctx).CacheContext()
+	_ctx = _ctx.WithEventManager(types.NewEventManager())
+	gas := make([]uint64, len(msgs))
+	for i, msg := range msgs {
+		msgCtx := _ctx.WithGasMeter(types.NewInfiniteGasMeter())
+		if _, err := c.msgHandler(msgCtx, msg); err != nil {
+			return nil, fmt.Errorf("simulating message %d: %w", i, err)
+		}
+		gas[i] = msgCtx.GasMeter().GasConsumed()
+	}
+	return gas, nil
+}
@@ -0,0 +1,92 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/types"
+)
+
+// SnapshotID identifies a snapshot of the simulated chain.
+type SnapshotID int
+
+// snapshot represents the state of the chain at the time of a snapshot.
+type snapshot struct {
+	id     SnapshotID
+	ctx    types.Context // The context whose multistore is branched.
+	events types.Events  // The events of the current block.
+	height int64         // The height of the current block.
+}
+
+// Snapshot records the current state of the chain, including the multistore,
+// the block time and height, and returns an identifier that can be used to
+// revert to this state.
+//
+// The snapshot is taken by branching the multistore of the current context
+// into a cache multistore. All subsequent state changes are written to the
+// branch until the chain is reverted.
+func (c *Client) Snapshot() SnapshotID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastSnapshotID++
+	s := snapshot{
+		id:     c.lastSnapshotID,
+		ctx:    c.ctx,
+		events: c.ctx.EventManager().Events(),
+		height: c.ctx.BlockHeight(),
+	}
+	c.snapshots = append(c.snapshots, s)
+	c.ctx = branchContext(s)
+	return s.id
+}
+
+// Revert reverts the chain to the state at the time of the snapshot with the
+// given identifier. Snapshots taken after the given snapshot are discarded.
+// The given snapshot stays valid, so that the chain can be reverted to it
+// multiple times.
+func (c *Client) Revert(id SnapshotID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := len(c.snapshots) - 1; i >= 0; i-- {
+		s := c.snapshots[i]
+		if s.id != id {
+			continue
+		}
+
+		c.snapshots = c.snapshots[:i+1]
+		// Blocks produced after the snapshot are discarded. They may have
+		// pushed older blocks out of the history.
+		if n := s.height - c.firstHeight; n >= 0 {
+			c.blocks = c.blocks[:n]
+		} else {
+			c.blocks, c.firstHeight = nil, s.height
+		}
+		c.ctx = branchContext(s)
+		return nil
+	}
+	return fmt.Errorf("unknown snapshot: %d", id)
+}
+
+// branchContext returns a copy of the snapshot context whose multistore is a
+// new branch of the snapshot multistore.
+func branchContext(s snapshot) types.Context {
+	em := types.NewEventManager()
+	em.EmitEvents(s.events)
+	ms := s.ctx.MultiStore().CacheMultiStore()
+	return s.ctx.WithMultiStore(ms).WithEventManager(em)
+}
@@ -15,6 +15,8 @@
 package io
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 
@@ -28,6 +30,10 @@ func WriteBytesUint16(w io.Writer, a []byte) error {
 	return pio.Encode(w, l, a)
 }
 
+// initialBufferSize is the maximum number of bytes that ReadBytesUint16
+// allocates before reading data.
+const initialBufferSize = 512
+
 // ReadBytesUint16 reads an uint16-length-encoded byte slice from the stream.
 func ReadBytesUint16(r io.Reader, a *[]byte) error {
 	var l uint16
@@ -36,6 +42,19 @@ func ReadBytesUint16(r io.Reader, a *[]byte) error {
 		return fmt.Errorf("reading length: %w", err)
 	}
 
-	*a = make([]byte, l)
-	return pio.Decode(r, a)
+	// The buffer grows as data arrives instead of being allocated for the
+	// claimed length upfront.
+	size := int(l)
+	if size > initialBufferSize {
+		size = initialBufferSize
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	if _, err := io.CopyN(buf, r, int64(l)); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("reading data: %w", err)
+	}
+	*a = buf.Bytes()
+	return nil
 }
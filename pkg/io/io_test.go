@@ -16,6 +16,8 @@ package io_test
 
 import (
 	"bytes"
+	"errors"
+	stdio "io"
 	"math/rand"
 	"testing"
 
@@ -47,3 +49,31 @@ func TestReadWriteBytesUint16(t *testing.T) {
 		t.Errorf("no equal: %v, %v", b, _b)
 	}
 }
+
+func TestReadBytesUint16Truncated(t *testing.T) {
+	var b []byte
+	err := io.ReadBytesUint16(bytes.NewReader([]byte{0xff, 0xff, 1, 2}), &b)
+	if !errors.Is(err, stdio.ErrUnexpectedEOF) {
+		t.Errorf("expected unexpected EOF, got %v", err)
+	}
+}
+
+func FuzzReadBytesUint16(f *testing.F) {
+	f.Add([]byte{0, 2, 1, 2})
+	f.Add([]byte{0xff, 0xff, 1})
+	f.Add([]byte{0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var b []byte
+		if err := io.ReadBytesUint16(bytes.NewReader(data), &b); err != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := io.WriteBytesUint16(&buf, b); err != nil {
+			t.Fatalf("writing: %v", err)
+		}
+		if !bytes.HasPrefix(data, buf.Bytes()) {
+			t.Errorf("encoding %v is not a prefix of %v", buf.Bytes(), data)
+		}
+	})
+}
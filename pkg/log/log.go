@@ -0,0 +1,112 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package log defines the structured logger used by the backend and adapters
+// for go-perun's logger and logrus.
+package log
+
+import (
+	"github.com/sirupsen/logrus"
+	plog "perun.network/go-perun/log"
+)
+
+// Keys of the structured fields attached by the backend.
+const (
+	ChannelIDKey = "channel_id" // Hex-encoded channel ID.
+	PartIdxKey   = "part_idx"   // Index of the channel participant.
+	FundingIDKey = "funding_id" // Hex-encoded funding ID.
+	AccountKey   = "account"    // Bech32-encoded address of an account.
+	TxHashKey    = "tx_hash"    // Hash of a transaction, if reported by the client.
+	HeightKey    = "height"     // Block height.
+	NodeKey      = "node"       // Index of a node of a failover client.
+)
+
+// Fields is a collection of structured fields.
+type Fields map[string]interface{}
+
+// Logger is a leveled logger with structured fields.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+
+	WithField(key string, value interface{}) Logger
+	WithFields(Fields) Logger
+	WithError(error) Logger
+}
+
+// Default returns the logger used by the backend if no logger is configured.
+// It logs to go-perun's framework logger.
+func Default() Logger {
+	return perunLogger{}
+}
+
+// FromPerun returns a logger that logs to the given go-perun logger.
+func FromPerun(l plog.Logger) Logger {
+	return perunLogger{l}
+}
+
+// perunLogger adapts a go-perun logger. If the wrapped logger is nil, the
+// go-perun framework logger is used at the time of logging, so that it can be
+// set after the backend has been created.
+type perunLogger struct {
+	plog.Logger
+}
+
+func (l perunLogger) get() plog.Logger {
+	if l.Logger == nil {
+		return plog.Get()
+	}
+	return l.Logger
+}
+
+func (l perunLogger) Debugf(format string, args ...interface{}) { l.get().Debugf(format, args...) }
+func (l perunLogger) Infof(format string, args ...interface{})  { l.get().Infof(format, args...) }
+func (l perunLogger) Warnf(format string, args ...interface{})  { l.get().Warnf(format, args...) }
+func (l perunLogger) Errorf(format string, args ...interface{}) { l.get().Errorf(format, args...) }
+
+func (l perunLogger) WithField(key string, value interface{}) Logger {
+	return perunLogger{l.get().WithField(key, value)}
+}
+
+func (l perunLogger) WithFields(fs Fields) Logger {
+	return perunLogger{l.get().WithFields(plog.Fields(fs))}
+}
+
+func (l perunLogger) WithError(err error) Logger {
+	return perunLogger{l.get().WithError(err)}
+}
+
+// FromLogrus returns a logger that logs to the given logrus logger or entry.
+func FromLogrus(l logrus.FieldLogger) Logger {
+	return logrusLogger{l}
+}
+
+// logrusLogger adapts a logrus logger.
+type logrusLogger struct {
+	logrus.FieldLogger
+}
+
+func (l logrusLogger) WithField(key string, value interface{}) Logger {
+	return logrusLogger{l.FieldLogger.WithField(key, value)}
+}
+
+func (l logrusLogger) WithFields(fs Fields) Logger {
+	return logrusLogger{l.FieldLogger.WithFields(logrus.Fields(fs))}
+}
+
+func (l logrusLogger) WithError(err error) Logger {
+	return logrusLogger{l.FieldLogger.WithError(err)}
+}
@@ -0,0 +1,96 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	wtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/cosmos/cosmos-sdk/client/grpc/tmservice"
+	client "github.com/perun-network/perun-cosmwasm-backend/pkg/cosmwasm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Client is a client that records the calls of the wrapped client. The gas
+// used by contract executions is recorded if the wrapped client reports it
+// in the header client.GasUsedHeader.
+type Client struct {
+	client.Client
+	metrics *Metrics
+}
+
+var _ client.Client = &Client{}
+
+// NewClient creates a client that records the calls of the given client.
+func NewClient(c client.Client, m *Metrics) *Client {
+	return &Client{Client: c, metrics: m}
+}
+
+// ExecuteContract executes a function on a contract.
+func (c *Client) ExecuteContract(ctx context.Context, in *wtypes.MsgExecuteContract, opts ...grpc.CallOption) (*wtypes.MsgExecuteContractResponse, error) {
+	var md metadata.MD
+	resp, err := c.Client.ExecuteContract(ctx, in, append(opts, grpc.Header(&md))...)
+	c.metrics.RPC("ExecuteContract", err)
+	if v := md.Get(client.GasUsedHeader); err == nil && len(v) > 0 {
+		if gas, parseErr := strconv.ParseUint(v[0], 10, 64); parseErr == nil {
+			c.metrics.ObserveGas(msgType(in.Msg), gas)
+		}
+	}
+	return resp, err
+}
+
+// SmartContractState performs a smart contract query.
+func (c *Client) SmartContractState(ctx context.Context, in *wtypes.QuerySmartContractStateRequest, opts ...grpc.CallOption) (*wtypes.QuerySmartContractStateResponse, error) {
+	resp, err := c.Client.SmartContractState(ctx, in, opts...)
+	c.metrics.RPC("SmartContractState", err)
+	return resp, err
+}
+
+// RawContractState performs a raw contract state query.
+func (c *Client) RawContractState(ctx context.Context, in *wtypes.QueryRawContractStateRequest, opts ...grpc.CallOption) (*wtypes.QueryRawContractStateResponse, error) {
+	resp, err := c.Client.RawContractState(ctx, in, opts...)
+	c.metrics.RPC("RawContractState", err)
+	return resp, err
+}
+
+// GetLatestBlock returns the latest block.
+func (c *Client) GetLatestBlock(ctx context.Context, in *tmservice.GetLatestBlockRequest, opts ...grpc.CallOption) (*tmservice.GetLatestBlockResponse, error) {
+	resp, err := c.Client.GetLatestBlock(ctx, in, opts...)
+	c.metrics.RPC("GetLatestBlock", err)
+	return resp, err
+}
+
+// GetSyncing returns whether the node is syncing.
+func (c *Client) GetSyncing(ctx context.Context, in *tmservice.GetSyncingRequest, opts ...grpc.CallOption) (*tmservice.GetSyncingResponse, error) {
+	resp, err := c.Client.GetSyncing(ctx, in, opts...)
+	c.metrics.RPC("GetSyncing", err)
+	return resp, err
+}
+
+// msgType returns the type of the given execute message, which is the name of
+// its single top-level field, or "unknown".
+func msgType(msg []byte) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &fields); err != nil || len(fields) != 1 {
+		return "unknown"
+	}
+	for name := range fields {
+		return name
+	}
+	return "unknown"
+}
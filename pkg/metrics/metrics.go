@@ -0,0 +1,227 @@
+//  Copyright 2021 PolyCrypt GmbH
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Package metrics provides Prometheus metrics for the on-chain operations of
+// the backend.
+//
+// All methods of Metrics may be called on a nil *Metrics, in which case they
+// do nothing. This allows components to record metrics unconditionally.
+package metrics
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/status"
+)
+
+// Namespace is the namespace of all metrics.
+const Namespace = "perun_cosmwasm"
+
+// Operations of the adjudicator.
+const (
+	OpDispute  = "dispute"
+	OpConclude = "conclude"
+	OpWithdraw = "withdraw"
+)
+
+// Types of adjudicator events.
+const (
+	EventRegistered = "registered"
+	EventConcluded  = "concluded"
+)
+
+// Components that poll the ledger.
+const (
+	ComponentFunder       = "funder"
+	ComponentSubscription = "subscription"
+	ComponentBlocks       = "blocks"
+)
+
+// Metrics holds the collectors of the backend.
+type Metrics struct {
+	fundingDuration     prometheus.Histogram
+	fundingFailures     prometheus.Counter
+	deposited           *prometheus.CounterVec
+	overfunded          *prometheus.CounterVec
+	adjudicatorCalls    *prometheus.CounterVec
+	adjudicatorFailures *prometheus.CounterVec
+	events              *prometheus.CounterVec
+	polls               *prometheus.CounterVec
+	gasUsed             *prometheus.HistogramVec
+	rpcCalls            *prometheus.CounterVec
+	rpcErrors           *prometheus.CounterVec
+}
+
+// New creates the collectors and registers them with the given registerer.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		fundingDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "funding_duration_seconds",
+			Help:      "Time from the start of funding until the channel is fully funded.",
+			Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10),
+		}),
+		fundingFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "funding_failures_total",
+			Help:      "Number of failed fundings.",
+		}),
+		deposited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "deposited_total",
+			Help:      "Amount deposited into channels, by denomination.",
+		}, []string{"denom"}),
+		overfunded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "overfunded_total",
+			Help:      "Amount found deposited in excess of the agreed share, by denomination.",
+		}, []string{"denom"}),
+		adjudicatorCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "adjudicator_calls_total",
+			Help:      "Number of adjudicator operations, by operation.",
+		}, []string{"op"}),
+		adjudicatorFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "adjudicator_failures_total",
+			Help:      "Number of failed adjudicator operations, by operation.",
+		}, []string{"op"}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "adjudicator_events_total",
+			Help:      "Number of observed adjudicator events, by type.",
+		}, []string{"type"}),
+		polls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "polls_total",
+			Help:      "Number of polling rounds, by component.",
+		}, []string{"component"}),
+		gasUsed: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "gas_used",
+			Help:      "Gas used by contract executions, by message type.",
+			Buckets:   prometheus.ExponentialBuckets(10000, 2, 10),
+		}, []string{"msg"}),
+		rpcCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "rpc_calls_total",
+			Help:      "Number of client calls, by method.",
+		}, []string{"method"}),
+		rpcErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "rpc_errors_total",
+			Help:      "Number of failed client calls, by method and gRPC status code.",
+		}, []string{"method", "code"}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.fundingDuration, m.fundingFailures, m.deposited, m.overfunded,
+		m.adjudicatorCalls, m.adjudicatorFailures, m.events, m.polls,
+		m.gasUsed, m.rpcCalls, m.rpcErrors,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// ObserveFunding records a funding that took the given duration and failed
+// with the given error, if not nil.
+func (m *Metrics) ObserveFunding(d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		m.fundingFailures.Inc()
+		return
+	}
+	m.fundingDuration.Observe(d.Seconds())
+}
+
+// AddDeposit records a deposit of the given coins.
+func (m *Metrics) AddDeposit(coins types.Coins) {
+	if m == nil {
+		return
+	}
+	for _, c := range coins {
+		f, _ := new(big.Float).SetInt(c.Amount.BigInt()).Float64()
+		m.deposited.WithLabelValues(c.Denom).Add(f)
+	}
+}
+
+// AddOverfunding records that a funding found the deposit exceeding the
+// agreed share by the given coins. Every funding that finds an excess adds
+// it, so repeated fundings of the same share add it repeatedly.
+func (m *Metrics) AddOverfunding(coins types.Coins) {
+	if m == nil {
+		return
+	}
+	for _, c := range coins {
+		f, _ := new(big.Float).SetInt(c.Amount.BigInt()).Float64()
+		m.overfunded.WithLabelValues(c.Denom).Add(f)
+	}
+}
+
+// AdjudicatorCall records an adjudicator operation that failed with the given
+// error, if not nil.
+func (m *Metrics) AdjudicatorCall(op string, err error) {
+	if m == nil {
+		return
+	}
+	m.adjudicatorCalls.WithLabelValues(op).Inc()
+	if err != nil {
+		m.adjudicatorFailures.WithLabelValues(op).Inc()
+	}
+}
+
+// Event records an adjudicator event of the given type.
+func (m *Metrics) Event(typ string) {
+	if m == nil {
+		return
+	}
+	m.events.WithLabelValues(typ).Inc()
+}
+
+// Poll records a polling round of the given component.
+func (m *Metrics) Poll(component string) {
+	if m == nil {
+		return
+	}
+	m.polls.WithLabelValues(component).Inc()
+}
+
+// ObserveGas records the gas used by a contract execution with the given
+// message type.
+func (m *Metrics) ObserveGas(msgType string, gas uint64) {
+	if m == nil {
+		return
+	}
+	m.gasUsed.WithLabelValues(msgType).Observe(float64(gas))
+}
+
+// RPC records a client call of the given method that failed with the given
+// error, if not nil.
+func (m *Metrics) RPC(method string, err error) {
+	if m == nil {
+		return
+	}
+	m.rpcCalls.WithLabelValues(method).Inc()
+	if err != nil {
+		m.rpcErrors.WithLabelValues(method, status.Code(err).String()).Inc()
+	}
+}